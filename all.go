@@ -0,0 +1,162 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// All returns a Lazy whose value holds the values of ls, in order. Its
+// initialization gets all of ls concurrently and fails with the first error
+// among them, cancelling the others; it is retried on the next Get according to
+// opts, and only the lazies that failed are initialized again, since the others
+// cache their values.
+func All[T any](ls []*Lazy[T], opts ...Option) *Lazy[[]T] {
+	return New(func(ctx context.Context) ([]T, error) {
+		values := make([]T, len(ls))
+		fs := make([]func(context.Context) error, len(ls))
+		for i, l := range ls {
+			fs[i] = get(l, &values[i])
+		}
+		if err := getAll(ctx, fs...); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}, opts...)
+}
+
+// Any returns a Lazy whose value is that of the first of ls to be initialized
+// successfully, as for redundant sources of the same data. Its initialization
+// gets all of ls concurrently and cancels the others once one succeeds. If all
+// of them fail, it fails with their errors joined with errors.Join, in the
+// order of ls, and is retried on the next Get according to opts. It fails if
+// ls is empty.
+func Any[T any](ls []*Lazy[T], opts ...Option) *Lazy[T] {
+	return New(func(ctx context.Context) (T, error) {
+		if len(ls) == 0 {
+			var zero T
+			return zero, errors.New("lazy: Any called with no inputs")
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		type result struct {
+			i     int
+			value T
+			err   error
+		}
+		results := make(chan result, len(ls))
+		for i, l := range ls {
+			go func() {
+				v, err := l.Get(ctx)
+				results <- result{i, v, err}
+			}()
+		}
+		errs := make([]error, len(ls))
+		for range ls {
+			r := <-results
+			if r.err == nil {
+				return r.value, nil
+			}
+			errs[r.i] = r.err
+		}
+		var zero T
+		return zero, errors.Join(errs...)
+	}, opts...)
+}
+
+// A Tuple2 holds two values of possibly different types.
+type Tuple2[A, B any] struct {
+	V1 A
+	V2 B
+}
+
+// A Tuple3 holds three values of possibly different types.
+type Tuple3[A, B, C any] struct {
+	V1 A
+	V2 B
+	V3 C
+}
+
+// All2 is like All for two lazies of possibly different types.
+func All2[A, B any](a *Lazy[A], b *Lazy[B], opts ...Option) *Lazy[Tuple2[A, B]] {
+	return New(func(ctx context.Context) (Tuple2[A, B], error) {
+		var t Tuple2[A, B]
+		err := getAll(ctx, get(a, &t.V1), get(b, &t.V2))
+		return t, err
+	}, opts...)
+}
+
+// All3 is like All for three lazies of possibly different types.
+func All3[A, B, C any](a *Lazy[A], b *Lazy[B], c *Lazy[C], opts ...Option) *Lazy[Tuple3[A, B, C]] {
+	return New(func(ctx context.Context) (Tuple3[A, B, C], error) {
+		var t Tuple3[A, B, C]
+		err := getAll(ctx, get(a, &t.V1), get(b, &t.V2), get(c, &t.V3))
+		return t, err
+	}, opts...)
+}
+
+// get returns a function that gets the value of l into v.
+func get[T any](l *Lazy[T], v *T) func(context.Context) error {
+	return func(ctx context.Context) error {
+		var err error
+		*v, err = l.Get(ctx)
+		return err
+	}
+}
+
+// getAll calls fs concurrently and waits for them. It returns the first error
+// among them, with which the context of the others is cancelled.
+func getAll(ctx context.Context, fs ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	for _, f := range fs {
+		wg.Go(func() {
+			if err := f(ctx); err != nil {
+				once.Do(func() {
+					first = err
+					cancel(err)
+				})
+			}
+		})
+	}
+	wg.Wait()
+	return first
+}
+
+// Zip2 is like All2 but combines the values of a and b with f, typically into
+// a struct of the caller's, so that code depending on both gets a single Lazy.
+// It fails, and retries, as All2 does.
+func Zip2[A, B, R any](a *Lazy[A], b *Lazy[B], f func(A, B) R, opts ...Option) *Lazy[R] {
+	return New(func(ctx context.Context) (R, error) {
+		var (
+			va A
+			vb B
+		)
+		if err := getAll(ctx, get(a, &va), get(b, &vb)); err != nil {
+			var zero R
+			return zero, err
+		}
+		return f(va, vb), nil
+	}, opts...)
+}
+
+// Zip3 is like Zip2 for three lazies.
+func Zip3[A, B, C, R any](a *Lazy[A], b *Lazy[B], c *Lazy[C], f func(A, B, C) R, opts ...Option) *Lazy[R] {
+	return New(func(ctx context.Context) (R, error) {
+		var (
+			va A
+			vb B
+			vc C
+		)
+		if err := getAll(ctx, get(a, &va), get(b, &vb), get(c, &vc)); err != nil {
+			var zero R
+			return zero, err
+		}
+		return f(va, vb, vc), nil
+	}, opts...)
+}
@@ -0,0 +1,143 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestAll(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var ls []*Lazy[int]
+		for i := range 3 {
+			ls = append(ls, New(func(ctx context.Context) (int, error) {
+				time.Sleep(time.Second)
+				return i, nil
+			}))
+		}
+
+		start := time.Now()
+		got, err := All(ls).Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, []int{0, 1, 2}) {
+			t.Fatalf("got %v, want [0 1 2]", got)
+		}
+		if d := time.Since(start); d != time.Second {
+			t.Fatalf("took %v, want the lazies initialized concurrently", d)
+		}
+	})
+}
+
+func TestAll_Error(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errFail := errors.New("fail")
+		failing := New(func(ctx context.Context) (string, error) { return "", errFail })
+		slow := New(func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 0, context.Cause(ctx)
+		})
+
+		if _, err := All2(slow, failing).Get(context.Background()); !errors.Is(err, errFail) {
+			t.Fatalf("got error %v, want %v", err, errFail)
+		}
+	})
+}
+
+func TestAll3(t *testing.T) {
+	a := New(func(ctx context.Context) (int, error) { return 1, nil })
+	b := New(func(ctx context.Context) (string, error) { return "b", nil })
+	c := New(func(ctx context.Context) (bool, error) { return true, nil })
+	got, err := All3(a, b, c).Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Tuple3[int, string, bool]{1, "b", true}); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestAny(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errFail := errors.New("fail")
+		failing := New(func(ctx context.Context) (string, error) { return "", errFail })
+		fast := New(func(ctx context.Context) (string, error) {
+			time.Sleep(time.Second)
+			return "fast", nil
+		})
+		var cancelled bool
+		slow := New(func(ctx context.Context) (string, error) {
+			select {
+			case <-time.After(time.Hour):
+				return "slow", nil
+			case <-ctx.Done():
+				cancelled = true
+				return "", ctx.Err()
+			}
+		})
+
+		if v, err := Any([]*Lazy[string]{failing, slow, fast}).Get(context.Background()); err != nil || v != "fast" {
+			t.Fatalf("got %q, %v, want fast, nil", v, err)
+		}
+		synctest.Wait()
+		if !cancelled {
+			t.Fatal("slow source not cancelled")
+		}
+	})
+}
+
+func TestAny_AllFail(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	a := New(func(ctx context.Context) (int, error) { return 0, errA })
+	b := New(func(ctx context.Context) (int, error) { return 0, errB })
+	_, err := Any([]*Lazy[int]{a, b}).Get(context.Background())
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("got error %v, want both errors", err)
+	}
+	if err.Error() != "a\nb" {
+		t.Fatalf("got error %q, want the errors in order", err)
+	}
+}
+
+func TestZip2(t *testing.T) {
+	type config struct {
+		host string
+		port int
+	}
+	errFail := errors.New("fail")
+	calls := 0
+	host := New(func(ctx context.Context) (string, error) { return "localhost", nil })
+	port := New(func(ctx context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, errFail
+		}
+		return 8080, nil
+	})
+	cfg := Zip2(host, port, func(h string, p int) config { return config{h, p} })
+
+	if _, err := cfg.Get(context.Background()); !errors.Is(err, errFail) {
+		t.Fatalf("got error %v, want %v", err, errFail)
+	}
+	got, err := cfg.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (config{"localhost", 8080}); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestAny_NoInputs(t *testing.T) {
+	l := Any[int](nil)
+	if _, err := l.Get(context.Background()); err == nil {
+		t.Fatal("got no error from Any with no inputs")
+	}
+	if l.State() == StateReady {
+		t.Fatalf("got state %v, want nothing cached", l.State())
+	}
+}
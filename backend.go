@@ -0,0 +1,53 @@
+package lazy
+
+import (
+	"context"
+	"time"
+)
+
+// A Backend is a second-level cache consulted by a Map for keys that are not
+// cached in memory, typically a remote store such as Redis or memcached shared
+// by several processes.
+type Backend[K, V any] interface {
+	// Get returns the value stored for key and whether there is one.
+	Get(ctx context.Context, key K) (V, bool, error)
+
+	// Set stores value for key. A ttl greater than zero is the time after
+	// which the backend may drop the value.
+	Set(ctx context.Context, key K, value V, ttl time.Duration) error
+
+	// Delete removes the value stored for key, if any.
+	Delete(ctx context.Context, key K) error
+}
+
+// loadKey loads the value for key from the backend if there is one, falling
+// back to the load function and writing its result back to the backend. Errors
+// of the backend are reported but do not fail the load.
+func (m *Map[K, V]) loadKey(ctx context.Context, key K) (V, error) {
+	if m.mo.Backend == nil {
+		return m.load(ctx, key)
+	}
+	v, ok, err := m.mo.Backend.Get(ctx, key)
+	if err != nil {
+		m.backendError(err)
+	} else if ok {
+		return v, nil
+	}
+
+	v, err = m.load(ctx, key)
+	if err != nil {
+		return v, err
+	}
+	if err := m.mo.Backend.Set(ctx, key, v, loadTTL(ctx, v)); err != nil {
+		m.backendError(err)
+	}
+	return v, nil
+}
+
+// backendError reports err, returned by the backend, to the OnBackendError
+// handler of m, if any.
+func (m *Map[K, V]) backendError(err error) {
+	if f := m.mo.OnBackendError; f != nil {
+		f(err)
+	}
+}
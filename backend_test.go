@@ -0,0 +1,107 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memBackend is a Backend that stores values in memory.
+type memBackend[K comparable, V any] struct {
+	mu     sync.Mutex
+	values map[K]V
+	ttls   map[K]time.Duration
+	err    error // returned by all methods if set
+}
+
+func newMemBackend[K comparable, V any]() *memBackend[K, V] {
+	return &memBackend[K, V]{values: make(map[K]V), ttls: make(map[K]time.Duration)}
+}
+
+func (b *memBackend[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.values[key]
+	return v, ok, b.err
+}
+
+func (b *memBackend[K, V]) Set(ctx context.Context, key K, value V, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return b.err
+	}
+	b.values[key] = value
+	b.ttls[key] = ttl
+	return nil
+}
+
+func (b *memBackend[K, V]) Delete(ctx context.Context, key K) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.values, key)
+	return b.err
+}
+
+func TestMapOptions_Backend(t *testing.T) {
+	var calls atomic.Int32
+	b := newMemBackend[string, string]()
+	b.values["remote"] = "from backend"
+
+	m := NewMapWithOptions(func(ctx context.Context, key string) (string, error) {
+		calls.Add(1)
+		if key == "short" {
+			SetTTL(ctx, time.Second)
+		}
+		return "loaded " + key, nil
+	}, MapOptions[string, string]{Backend: b}, WithTTL(time.Minute))
+
+	ctx := context.Background()
+	if v, _ := m.Get(ctx, "remote"); v != "from backend" {
+		t.Fatalf("got %q, want %q", v, "from backend")
+	}
+	if got := calls.Load(); got != 0 {
+		t.Fatalf("load called %d times for a key in the backend, want 0", got)
+	}
+
+	if v, _ := m.Get(ctx, "local"); v != "loaded local" {
+		t.Fatalf("got %q, want %q", v, "loaded local")
+	}
+	m.Get(ctx, "short")
+	if v, ttl := b.values["local"], b.ttls["local"]; v != "loaded local" || ttl != time.Minute {
+		t.Fatalf("backend holds (%q, %v), want (%q, %v)", v, ttl, "loaded local", time.Minute)
+	}
+	if ttl := b.ttls["short"]; ttl != time.Second {
+		t.Fatalf("backend holds TTL %v, want %v", ttl, time.Second)
+	}
+
+	m.Forget("local")
+	if _, ok := b.values["local"]; ok {
+		t.Fatal("Forget did not delete the key from the backend")
+	}
+}
+
+func TestMapOptions_BackendErrors(t *testing.T) {
+	errBackend := errors.New("backend down")
+	b := newMemBackend[string, string]()
+	b.err = errBackend
+
+	var reported []error
+	m := NewMapWithOptions(func(ctx context.Context, key string) (string, error) {
+		return key, nil
+	}, MapOptions[string, string]{
+		Backend:        b,
+		OnBackendError: func(err error) { reported = append(reported, err) },
+	})
+
+	// The load falls back to the load function.
+	if v, err := m.Get(context.Background(), "a"); err != nil || v != "a" {
+		t.Fatalf("got (%q, %v), want (%q, nil)", v, err, "a")
+	}
+	if len(reported) != 2 || !errors.Is(reported[0], errBackend) || !errors.Is(reported[1], errBackend) {
+		t.Fatalf("got reported errors %v, want the failed Get and Set", reported)
+	}
+}
@@ -0,0 +1,125 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// NewBatchMap returns a Map that loads values in batches, in the style of a
+// dataloader. Keys requested within window of the first key of a batch, up to
+// maxBatch keys, are collected and loaded with a single call of batch. The
+// Map's usual deduplication and caching apply per key on top of batching.
+//
+// batch is called with the context of the first key's caller, without its
+// cancellation, so that callers giving up do not fail the whole batch. A key
+// absent from the map returned by batch fails with ErrMissing. If batch
+// returns an error or panics, all keys of the batch fail with it, the latter
+// as a *PanicError. A maxBatch of zero or less means no limit.
+func NewBatchMap[K comparable, V any](batch func(context.Context, []K) (map[K]V, error), window time.Duration, maxBatch int, opts ...Option) *Map[K, V] {
+	b := &batcher[K, V]{
+		fn:       batch,
+		window:   window,
+		maxBatch: maxBatch,
+	}
+	return NewMap(b.load, opts...)
+}
+
+// A batcher collects keys into batches for a batch load function.
+type batcher[K comparable, V any] struct {
+	fn       func(context.Context, []K) (map[K]V, error)
+	window   time.Duration
+	maxBatch int
+
+	mu  sync.Mutex
+	cur *keyBatch[K, V] // batch collecting keys, if any
+}
+
+// A keyBatch is a set of keys loaded together.
+type keyBatch[K comparable, V any] struct {
+	ctx   context.Context
+	keys  []K
+	timer *time.Timer
+	done  chan struct{}
+
+	// dispatched is guarded by the batcher's mutex.
+	dispatched bool
+
+	// values and err are set before done is closed.
+	values map[K]V
+	err    error
+}
+
+// load adds key to the current batch and waits for the batch to be loaded.
+func (b *batcher[K, V]) load(ctx context.Context, key K) (V, error) {
+	b.mu.Lock()
+	kb := b.cur
+	if kb == nil {
+		kb = &keyBatch[K, V]{
+			ctx:  context.WithoutCancel(ctx),
+			done: make(chan struct{}),
+		}
+		kb.timer = time.AfterFunc(b.window, func() { b.dispatch(kb) })
+		b.cur = kb
+	}
+	kb.keys = append(kb.keys, key)
+	full := b.maxBatch > 0 && len(kb.keys) >= b.maxBatch && b.takeLocked(kb)
+	b.mu.Unlock()
+
+	if full {
+		kb.timer.Stop()
+		go b.run(kb)
+	}
+
+	select {
+	case <-kb.done:
+	case <-ctx.Done():
+		var zero V
+		return zero, context.Cause(ctx)
+	}
+	if kb.err != nil {
+		var zero V
+		return zero, kb.err
+	}
+	v, ok := kb.values[key]
+	if !ok {
+		return v, fmt.Errorf("%w: %v", ErrMissing, key)
+	}
+	return v, nil
+}
+
+// dispatch loads kb unless it has already been dispatched.
+func (b *batcher[K, V]) dispatch(kb *keyBatch[K, V]) {
+	b.mu.Lock()
+	ok := b.takeLocked(kb)
+	b.mu.Unlock()
+	if ok {
+		b.run(kb)
+	}
+}
+
+// takeLocked closes kb to new keys and reports whether it had not already been
+// dispatched. It must be called with b.mu held.
+func (b *batcher[K, V]) takeLocked(kb *keyBatch[K, V]) bool {
+	if kb.dispatched {
+		return false
+	}
+	kb.dispatched = true
+	if b.cur == kb {
+		b.cur = nil
+	}
+	return true
+}
+
+// run calls the batch function for the keys of kb.
+func (b *batcher[K, V]) run(kb *keyBatch[K, V]) {
+	defer close(kb.done)
+	defer func() {
+		if r := recover(); r != nil {
+			kb.err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	kb.values, kb.err = b.fn(kb.ctx, kb.keys)
+}
@@ -0,0 +1,149 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestNewBatchMap_CoalescesKeys(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mu sync.Mutex
+		var batches [][]int
+
+		m := NewBatchMap(func(ctx context.Context, keys []int) (map[int]int, error) {
+			mu.Lock()
+			batches = append(batches, slices.Sorted(slices.Values(keys)))
+			mu.Unlock()
+			values := make(map[int]int)
+			for _, k := range keys {
+				values[k] = k * k
+			}
+			return values, nil
+		}, 10*time.Millisecond, 0)
+
+		var wg sync.WaitGroup
+		for key := range 4 {
+			wg.Go(func() {
+				if v, err := m.Get(context.Background(), key); err != nil || v != key*key {
+					t.Errorf("Get(%d) = (%d, %v)", key, v, err)
+				}
+			})
+		}
+		wg.Wait()
+
+		if len(batches) != 1 || !slices.Equal(batches[0], []int{0, 1, 2, 3}) {
+			t.Fatalf("got batches %v, want [[0 1 2 3]]", batches)
+		}
+
+		// Cached keys are not loaded again.
+		m.Get(context.Background(), 2)
+		if len(batches) != 1 {
+			t.Fatalf("got %d batches after cached read, want 1", len(batches))
+		}
+	})
+}
+
+func TestNewBatchMap_MaxBatch(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mu sync.Mutex
+		var sizes []int
+
+		m := NewBatchMap(func(ctx context.Context, keys []int) (map[int]int, error) {
+			mu.Lock()
+			sizes = append(sizes, len(keys))
+			mu.Unlock()
+			values := make(map[int]int)
+			for _, k := range keys {
+				values[k] = k
+			}
+			return values, nil
+		}, time.Hour, 2)
+
+		var wg sync.WaitGroup
+		for key := range 4 {
+			wg.Go(func() { m.Get(context.Background(), key) })
+		}
+		wg.Wait()
+
+		// Full batches are dispatched without waiting for the window.
+		if !slices.Equal(sizes, []int{2, 2}) {
+			t.Fatalf("got batch sizes %v, want [2 2]", sizes)
+		}
+	})
+}
+
+func TestNewBatchMap_MaxBatchConcurrent(t *testing.T) {
+	// Not in a bubble, so that the Gets can run in parallel with the
+	// dispatch of full batches, when keys must not be added to them.
+	const maxBatch, n = 3, 1000
+	var mu sync.Mutex
+	var loaded []int
+	m := NewBatchMap(func(ctx context.Context, keys []int) (map[int]int, error) {
+		if len(keys) > maxBatch {
+			t.Errorf("got a batch of %d keys, want at most %d", len(keys), maxBatch)
+		}
+		mu.Lock()
+		loaded = append(loaded, keys...)
+		mu.Unlock()
+		values := make(map[int]int)
+		for _, k := range keys {
+			values[k] = k
+		}
+		return values, nil
+	}, time.Millisecond, maxBatch)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for key := range n {
+		wg.Go(func() {
+			<-start
+			if v, err := m.Get(context.Background(), key); err != nil || v != key {
+				t.Errorf("Get(%d) = (%d, %v)", key, v, err)
+			}
+		})
+	}
+	close(start)
+	wg.Wait()
+	slices.Sort(loaded)
+	if len(loaded) != n || len(slices.Compact(loaded)) != n {
+		t.Fatalf("loaded %d keys, want each of the %d keys once", len(loaded), n)
+	}
+}
+
+func TestNewBatchMap_Errors(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errBatch := errors.New("batch failed")
+
+		m := NewBatchMap(func(ctx context.Context, keys []string) (map[string]int, error) {
+			if slices.Contains(keys, "fail") {
+				return nil, errBatch
+			}
+			return map[string]int{"present": 1}, nil
+		}, time.Millisecond, 0)
+
+		if _, err := m.Get(context.Background(), "missing"); !errors.Is(err, ErrMissing) {
+			t.Fatalf("got error %v, want %v", err, ErrMissing)
+		}
+		if _, err := m.Get(context.Background(), "fail"); !errors.Is(err, errBatch) {
+			t.Fatalf("got error %v, want %v", err, errBatch)
+		}
+	})
+}
+
+func TestNewBatchMap_Panic(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		m := NewBatchMap(func(ctx context.Context, keys []string) (map[string]int, error) {
+			panic("boom")
+		}, time.Millisecond, 0)
+
+		var perr *PanicError
+		if _, err := m.Get(context.Background(), "a"); !errors.As(err, &perr) {
+			t.Fatalf("got error %v, want *PanicError", err)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package lazy
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A Checker reports whether it is ready to serve, for aggregation into the
+// readiness endpoint of a service. Lazy, Map and Group implement Checker.
+type Checker interface {
+	// Check returns nil if the Checker is ready, or otherwise an error,
+	// wrapping ErrNotReady, that describes why not.
+	Check() error
+}
+
+// Check returns nil if the value of l is cached, and otherwise an error
+// reporting that l is not initialized, is being initialized, or that
+// its last initialization failed, wrapping the error of that initialization.
+func (l *Lazy[T]) Check() error {
+	switch {
+	case l.value.Load() != nil:
+		return nil
+	case l.closed.Load():
+		return fmt.Errorf("%w: %w", ErrNotReady, ErrClosed)
+	case l.busy():
+		return fmt.Errorf("%w: initialization in progress", ErrNotReady)
+	}
+	if p := l.failure.Load(); p != nil {
+		return fmt.Errorf("%w: initialization failed: %w", ErrNotReady, *p)
+	}
+	return fmt.Errorf("%w: not initialized", ErrNotReady)
+}
+
+// Check returns nil unless m serves values that are stale past their TTL
+// because reloading them failed, in which case it returns an error with their
+// number, wrapping the error of one of the reloads. A Map that has not yet
+// loaded a value is ready, since it loads values on demand.
+func (m *Map[K, V]) Check() error {
+	var (
+		stale int
+		cause error
+	)
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.Lock()
+		for elem := sh.lru.Front(); elem != nil; elem = elem.Next() {
+			e := elem.Value.(*mapEntry[K, V])
+			if e.stale == nil {
+				continue
+			}
+			if p := e.l.failure.Load(); p != nil {
+				stale++
+				cause = *p
+			}
+		}
+		sh.mu.Unlock()
+	}
+	if stale == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d values stale past their TTL: %w", ErrNotReady, stale, cause)
+}
+
+// Check returns nil if every member of g is ready, and otherwise the errors of
+// those that are not, each as a *MemberError, joined with errors.Join.
+// Members that do not implement Checker are ready once their State is
+// StateReady.
+func (g *Group) Check() error {
+	g.mu.Lock()
+	members := g.members
+	g.mu.Unlock()
+
+	var errs []error
+	for _, m := range members {
+		var err error
+		if c, ok := m.h.(Checker); ok {
+			err = c.Check()
+		} else if s := m.h.State(); s != StateReady {
+			err = fmt.Errorf("%w: %v", ErrNotReady, s)
+		}
+		if err != nil {
+			errs = append(errs, &MemberError{Name: m.name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestLazy_Check(t *testing.T) {
+	errFailed := errors.New("failed")
+	fail := true
+	l := New(func(ctx context.Context) (int, error) {
+		if fail {
+			return 0, errFailed
+		}
+		return 1, nil
+	})
+
+	if err := l.Check(); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("got error %v before initialization, want %v", err, ErrNotReady)
+	}
+	l.Prime(context.Background())
+	if err := l.Check(); !errors.Is(err, ErrNotReady) || !errors.Is(err, errFailed) {
+		t.Fatalf("got error %v after failure, want %v wrapping %v", err, ErrNotReady, errFailed)
+	}
+	fail = false
+	l.Prime(context.Background())
+	if err := l.Check(); err != nil {
+		t.Fatalf("got error %v after initialization, want nil", err)
+	}
+}
+
+func TestMap_Check(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errFailed := errors.New("failed")
+		fail := false
+		m := NewMap(func(ctx context.Context, key string) (string, error) {
+			if fail {
+				return "", errFailed
+			}
+			return key, nil
+		}, WithTTL(time.Minute), WithStaleWhileRevalidate(time.Hour))
+
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		if err := m.Check(); err != nil {
+			t.Fatalf("got error %v, want nil", err)
+		}
+
+		fail = true
+		time.Sleep(2 * time.Minute)
+		m.Get(ctx, "a") // serves the stale value and revalidates it
+		synctest.Wait()
+		if err := m.Check(); !errors.Is(err, ErrNotReady) || !errors.Is(err, errFailed) {
+			t.Fatalf("got error %v, want %v wrapping %v", err, ErrNotReady, errFailed)
+		}
+	})
+}
+
+func TestGroup_Check(t *testing.T) {
+	ready := New(func(ctx context.Context) (int, error) { return 1, nil })
+	idle := New(func(ctx context.Context) (int, error) { return 1, nil })
+	var g Group
+	g.Add("ready", ready)
+	g.Add("idle", idle)
+
+	ready.Prime(context.Background())
+	err := g.Check()
+	if !errors.Is(err, ErrNotReady) {
+		t.Fatalf("got error %v, want %v", err, ErrNotReady)
+	}
+	if want := "idle: lazy: not ready: not initialized"; err.Error() != want {
+		t.Fatalf("got error %q, want %q", err, want)
+	}
+
+	idle.Prime(context.Background())
+	if err := g.Check(); err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+}
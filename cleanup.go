@@ -0,0 +1,99 @@
+package lazy
+
+import "context"
+
+// NewWithCleanup is like New, but f also returns a function that releases the
+// resources of the value it returns, for values that do not implement
+// io.Closer. The cleanup function is called when the value is discarded: by
+// Close, Invalidate or Set, or because a concurrent Set or hedged attempt
+// took precedence over it. It may be nil.
+func NewWithCleanup[T any](f func(context.Context) (T, func(context.Context) error, error), opts ...Option) *Lazy[T] {
+	return newLazy(f, newOptions(opts), new(lazyCounters))
+}
+
+// noCleanup adapts f to return no cleanup function.
+func noCleanup[T any](f func(context.Context) (T, error)) func(context.Context) (T, func(context.Context) error, error) {
+	return func(ctx context.Context) (T, func(context.Context) error, error) {
+		v, err := f(ctx)
+		return v, nil, err
+	}
+}
+
+// withCleanup is a value along with its cleanup function.
+type withCleanup[T any] struct {
+	value   T
+	cleanup func(context.Context) error
+}
+
+// swap caches p as the value of l, which has no cleanup function, and returns
+// the cleanup function of the value it replaces, if any, and the generation of
+// l after the swap. It does nothing if l is closed.
+func (l *Lazy[T]) swap(p *T) (func(context.Context) error, uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed.Load() {
+		return nil, 0
+	}
+	l.value.Store(p)
+	cleanup := l.cleanup
+	l.holdLocked(p, nil)
+	l.cachedLocked(p)
+	return cleanup, l.generation.Load()
+}
+
+// holdLocked records cleanup as the cleanup function of the cached value p. It
+// must be called with l.mu held.
+func (l *Lazy[T]) holdLocked(p *T, cleanup func(context.Context) error) {
+	l.cleanup = cleanup
+	if l.held != nil {
+		l.held.value, l.held.cleanup = p, cleanup
+	}
+}
+
+// A heldValue is the value of a Lazy and its cleanup function, to release once
+// the Lazy has been collected.
+type heldValue[T any] struct {
+	value   *T
+	cleanup func(context.Context) error
+}
+
+// release releases the value of h as Close does, ignoring errors.
+func (h *heldValue[T]) release() {
+	if h.value != nil {
+		releaseValue(*h.value, h.cleanup)
+	}
+}
+
+// runCleanup calls cleanup, if it is not nil, ignoring its error.
+func runCleanup(cleanup func(context.Context) error) {
+	if cleanup != nil {
+		cleanup(context.Background())
+	}
+}
+
+// NewMapWithCleanup is like NewMap, but load also returns a function that
+// releases the resources of the value it returns. The cleanup function is
+// called with context.Background once the value is removed from the Map, for
+// any EvictReason, and its error is ignored. A value whose key is removed while it is being loaded is released
+// as soon as the load finishes. It may be nil.
+func NewMapWithCleanup[K comparable, V any](load func(context.Context, K) (V, func(context.Context) error, error), opts ...Option) *Map[K, V] {
+	return NewMap(func(ctx context.Context, key K) (V, error) {
+		v, cleanup, err := load(ctx, key)
+		if err == nil {
+			setCleanup(ctx, cleanup)
+		}
+		return v, err
+	}, opts...)
+}
+
+// setCleanup sets the cleanup function of the value being loaded by a Map with
+// ctx.
+func setCleanup(ctx context.Context, cleanup func(context.Context) error) {
+	state, ok := ctx.Value(loadStateKey{}).(*loadState)
+	if !ok {
+		return
+	}
+	state.mu.Lock()
+	state.cleanup = cleanup
+	state.mu.Unlock()
+}
@@ -0,0 +1,165 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestNewWithCleanup(t *testing.T) {
+	var cleaned []int
+	n := 0
+	l := NewWithCleanup(func(ctx context.Context) (int, func(context.Context) error, error) {
+		n++
+		v := n
+		return v, func(context.Context) error {
+			cleaned = append(cleaned, v)
+			return nil
+		}, nil
+	})
+
+	ctx := context.Background()
+	l.Get(ctx)
+	l.Invalidate()
+	l.Get(ctx)
+	l.Set(10)
+	l.Invalidate()
+	l.Get(ctx)
+	if err := l.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(cleaned, want) {
+		t.Fatalf("got cleaned %v, want %v", cleaned, want)
+	}
+}
+
+func TestNewWithCleanup_CloseError(t *testing.T) {
+	errCleanup := errors.New("cleanup failed")
+	l := NewWithCleanup(func(ctx context.Context) (int, func(context.Context) error, error) {
+		return 1, func(context.Context) error { return errCleanup }, nil
+	})
+	l.Prime(context.Background())
+	if err := l.Close(context.Background()); !errors.Is(err, errCleanup) {
+		t.Fatalf("got error %v, want %v", err, errCleanup)
+	}
+}
+
+func TestNewWithCleanup_Hedged(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var (
+			mu      sync.Mutex
+			cleaned []int
+			calls   int
+		)
+		l := NewWithCleanup(func(ctx context.Context) (int, func(context.Context) error, error) {
+			mu.Lock()
+			calls++
+			v := calls
+			mu.Unlock()
+			// The first call is slower than the second one.
+			time.Sleep(time.Duration(3-v) * time.Second)
+			return v, func(context.Context) error {
+				mu.Lock()
+				cleaned = append(cleaned, v)
+				mu.Unlock()
+				return nil
+			}, nil
+		}, WithHedging(time.Second/2, 2))
+
+		v, err := l.Get(context.Background())
+		if err != nil || v != 2 {
+			t.Fatalf("got (%v, %v), want (2, nil)", v, err)
+		}
+		time.Sleep(time.Second)
+		synctest.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		if !slices.Equal(cleaned, []int{1}) {
+			t.Fatalf("got cleaned %v, want [1]", cleaned)
+		}
+	})
+}
+
+func TestNewMapWithCleanup(t *testing.T) {
+	var cleaned []string
+	m := NewMapWithCleanup(func(ctx context.Context, key string) (string, func(context.Context) error, error) {
+		return key, func(context.Context) error {
+			cleaned = append(cleaned, key)
+			return nil
+		}, nil
+	}, WithCapacity(1), WithShards(1))
+
+	ctx := context.Background()
+	m.Get(ctx, "a")
+	m.Get(ctx, "b") // evicts a
+	m.Forget("b")
+	if want := []string{"a", "b"}; !slices.Equal(cleaned, want) {
+		t.Fatalf("got cleaned %v, want %v", cleaned, want)
+	}
+}
+
+func TestNewMapWithCleanup_ForgetDuringLoad(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var loads, cleanups atomic.Int32
+		proceed := make(chan struct{})
+		m := NewMapWithCleanup(func(ctx context.Context, key string) (string, func(context.Context) error, error) {
+			if loads.Add(1) == 1 {
+				<-proceed
+			}
+			return key, func(context.Context) error {
+				cleanups.Add(1)
+				return nil
+			}, nil
+		})
+
+		ctx := context.Background()
+		go m.Get(ctx, "a")
+		synctest.Wait()
+		m.Forget("a")
+		close(proceed)
+		synctest.Wait()
+
+		// The value loaded for the forgotten entry is released.
+		if got := cleanups.Load(); got != 1 {
+			t.Fatalf("got %d cleanups after the load of a forgotten key, want 1", got)
+		}
+		m.Get(ctx, "a")
+		m.Forget("a")
+		if l, c := loads.Load(), cleanups.Load(); l != 2 || c != 2 {
+			t.Fatalf("got %d loads and %d cleanups, want 2 of each", l, c)
+		}
+	})
+}
+
+func TestWithCollectCleanup(t *testing.T) {
+	cleaned := make(chan int, 1)
+	func() {
+		l := NewWithCleanup(func(ctx context.Context) (int, func(context.Context) error, error) {
+			return 1, func(context.Context) error {
+				cleaned <- 1
+				return nil
+			}, nil
+		}, WithCollectCleanup())
+		if err := l.Prime(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	timeout := time.After(10 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case <-cleaned:
+			return
+		case <-timeout:
+			t.Fatal("value not released after the lazy was collected")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
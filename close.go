@@ -0,0 +1,113 @@
+package lazy
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync/atomic"
+)
+
+// A ContextCloser is a value that releases its resources when closed, like an
+// io.Closer, but honoring a context.
+type ContextCloser interface {
+	Close(ctx context.Context) error
+}
+
+// initSeq numbers the initializations of all lazies.
+var initSeq atomic.Uint64
+
+// initialized records that l has just been initialized.
+func (l *Lazy[T]) initialized() {
+	l.seq.Store(initSeq.Add(1))
+}
+
+// Close closes l for good, as during the shutdown of a program: all later calls
+// of Get and TryGet return ErrClosed without calling the initialization
+// function, and Set and Invalidate have no effect. The value of an
+// initialization in progress is released once it finishes.
+//
+// Close discards the value of l, if any, and releases it by calling its cleanup
+// function, if it was returned along with the value to NewWithCleanup, or else
+// its Close method, if it implements ContextCloser or io.Closer. Close returns
+// the error of the call. Closing l again does nothing.
+func (l *Lazy[T]) Close(ctx context.Context) error {
+	l.mu.Lock()
+	l.closed.Store(true)
+	p := l.value.Swap(nil)
+	cleanup := l.cleanup
+	l.holdLocked(nil, nil)
+	l.closeWatchersLocked()
+	l.mu.Unlock()
+	switch {
+	case cleanup != nil:
+		return cleanup(ctx)
+	case p != nil:
+		return closeValue(ctx, *p)
+	}
+	return nil
+}
+
+// releaseValue releases v by calling cleanup, if it is not nil, or else by
+// closing v, ignoring errors.
+func releaseValue(v any, cleanup func(context.Context) error) {
+	if cleanup != nil {
+		cleanup(context.Background())
+		return
+	}
+	closeValue(context.Background(), v)
+}
+
+// closeValue closes v if it implements ContextCloser or io.Closer.
+func closeValue(ctx context.Context, v any) error {
+	switch c := v.(type) {
+	case ContextCloser:
+		return c.Close(ctx)
+	case io.Closer:
+		return c.Close()
+	}
+	return nil
+}
+
+// initOrder returns the sequence number of the latest initialization of l, or
+// zero if it has never been initialized.
+func (l *Lazy[T]) initOrder() uint64 {
+	return l.seq.Load()
+}
+
+// Close closes the members of g that implement ContextCloser, such as lazies,
+// in the reverse order of their initialization, so that values are closed
+// before the values they depend on. Lazies that were never initialized have no
+// value to release and, like the other members, whose initialization order is
+// unknown, are closed last, in the reverse order in which they were added.
+// Close returns the errors of the members that failed to close, each as a
+// *MemberError, joined with errors.Join.
+func (g *Group) Close(ctx context.Context) error {
+	g.mu.Lock()
+	members := slices.Clone(g.members)
+	g.mu.Unlock()
+
+	members = slices.DeleteFunc(members, func(m groupMember) bool {
+		_, ok := m.h.(ContextCloser)
+		return !ok
+	})
+	initOrder := func(m groupMember) uint64 {
+		if o, ok := m.h.(interface{ initOrder() uint64 }); ok {
+			return o.initOrder()
+		}
+		return 0
+	}
+	slices.Reverse(members)
+	slices.SortStableFunc(members, func(a, b groupMember) int {
+		return cmp.Compare(initOrder(b), initOrder(a))
+	})
+
+	var errs []error
+	for _, m := range members {
+		if err := m.h.(ContextCloser).Close(ctx); err != nil {
+			errs = append(errs, &MemberError{Name: m.name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,178 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/synctest"
+)
+
+// closer records its name in closed when closed.
+type closer struct {
+	name   string
+	closed *[]string
+	err    error
+}
+
+func (c *closer) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.err
+}
+
+func TestLazy_Close(t *testing.T) {
+	var closed []string
+	l := New(func(ctx context.Context) (*closer, error) {
+		return &closer{name: "a", closed: &closed}, nil
+	})
+
+	if err := l.Prime(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(closed, []string{"a"}) {
+		t.Fatalf("got closed %v, want [a]", closed)
+	}
+	if l.State() != StateClosed {
+		t.Fatalf("got state %v after Close, want %v", l.State(), StateClosed)
+	}
+}
+
+func TestLazy_CloseUninitialized(t *testing.T) {
+	var closed []string
+	l := New(func(ctx context.Context) (*closer, error) {
+		return &closer{name: "a", closed: &closed}, nil
+	})
+
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error %v after Close, want %v", err, ErrClosed)
+	}
+	if _, err := l.TryGet(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error %v from TryGet after Close, want %v", err, ErrClosed)
+	}
+	l.Set(&closer{name: "b", closed: &closed})
+	if _, err := l.Get(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error %v after Set, want %v", err, ErrClosed)
+	}
+	if len(closed) != 0 {
+		t.Fatalf("closed %v, want none", closed)
+	}
+}
+
+func TestLazy_CloseInFlight(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var closed []string
+		proceed := make(chan struct{})
+		l := New(func(ctx context.Context) (*closer, error) {
+			<-proceed
+			return &closer{name: "a", closed: &closed}, nil
+		})
+
+		errc := make(chan error)
+		go func() {
+			_, err := l.Get(context.Background())
+			errc <- err
+		}()
+		synctest.Wait()
+		l.Close(context.Background())
+		close(proceed)
+		if err := <-errc; !errors.Is(err, ErrClosed) {
+			t.Fatalf("got error %v, want %v", err, ErrClosed)
+		}
+		if !slices.Equal(closed, []string{"a"}) {
+			t.Fatalf("got closed %v, want the in-flight value closed", closed)
+		}
+	})
+}
+
+type ctxCloser struct{ ctx context.Context }
+
+func (c *ctxCloser) Close(ctx context.Context) error {
+	c.ctx = ctx
+	return nil
+}
+
+func TestLazy_CloseContext(t *testing.T) {
+	c := new(ctxCloser)
+	l := New(func(ctx context.Context) (*ctxCloser, error) { return c, nil })
+	l.Prime(context.Background())
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, true)
+	if err := l.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.ctx != ctx {
+		t.Fatal("Close was not called with the context")
+	}
+}
+
+func TestGroup_Close(t *testing.T) {
+	var closed []string
+	errB := errors.New("b failed")
+	newCloser := func(name string, err error) *Lazy[*closer] {
+		return New(func(ctx context.Context) (*closer, error) {
+			return &closer{name: name, closed: &closed, err: err}, nil
+		})
+	}
+	a, b, c, unused := newCloser("a", nil), newCloser("b", errB), newCloser("c", nil), newCloser("unused", nil)
+
+	var g Group
+	g.Add("c", c, "b")
+	g.Add("a", a)
+	g.Add("unused", unused)
+	g.Add("b", b, "a")
+	for _, l := range []*Lazy[*closer]{a, b, c} {
+		if err := l.Prime(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := g.Close(context.Background())
+	if !errors.Is(err, errB) {
+		t.Fatalf("got error %v, want %v", err, errB)
+	}
+	if !slices.Equal(closed, []string{"c", "b", "a"}) {
+		t.Fatalf("got closed %v, want [c b a]", closed)
+	}
+}
+
+// closerHandle is a Handle that is not a Lazy but implements ContextCloser.
+type closerHandle struct {
+	closer
+}
+
+func (h *closerHandle) State() State                    { return StateReady }
+func (h *closerHandle) Prime(ctx context.Context) error { return nil }
+func (h *closerHandle) Invalidate()                     {}
+func (h *closerHandle) Close(ctx context.Context) error { return h.closer.Close() }
+
+func TestGroup_CloseOtherClosers(t *testing.T) {
+	var closed []string
+	a := New(func(ctx context.Context) (*closer, error) {
+		return &closer{name: "a", closed: &closed}, nil
+	})
+
+	var g Group
+	g.Add("x", &closerHandle{closer{name: "x", closed: &closed}})
+	g.Add("a", a)
+	g.Add("y", &closerHandle{closer{name: "y", closed: &closed}})
+	if err := a.Prime(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(closed, []string{"a", "y", "x"}) {
+		t.Fatalf("got closed %v, want [a y x]", closed)
+	}
+}
@@ -0,0 +1,84 @@
+package lazy
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// A Codec serializes values, so that they can be stored outside the process,
+// as by a Backend or a snapshot of a Map.
+type Codec interface {
+	// Marshal returns the encoding of v.
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes data into the value pointed to by v.
+	Unmarshal(data []byte, v any) error
+}
+
+// GobCodec is a Codec using encoding/gob.
+type GobCodec struct{}
+
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// JSONCodec is a Codec using encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// NewCodecBackend returns a Backend storing values in b encoded with c, so
+// that a Map of any value type can use a backend that stores bytes. A value
+// that c cannot encode or decode makes Set or Get fail with an error
+// describing it; a Map treats such errors as cache misses, reporting them
+// rather than failing the Get.
+func NewCodecBackend[K, V any](b Backend[K, []byte], c Codec) Backend[K, V] {
+	return codecBackend[K, V]{b, c}
+}
+
+type codecBackend[K, V any] struct {
+	b Backend[K, []byte]
+	c Codec
+}
+
+func (cb codecBackend[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	var v V
+	data, ok, err := cb.b.Get(ctx, key)
+	if err != nil || !ok {
+		return v, ok, err
+	}
+	if err := cb.c.Unmarshal(data, &v); err != nil {
+		return v, false, fmt.Errorf("lazy: decoding value of key %v: %w", key, err)
+	}
+	return v, true, nil
+}
+
+func (cb codecBackend[K, V]) Set(ctx context.Context, key K, value V, ttl time.Duration) error {
+	data, err := cb.c.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("lazy: encoding value of key %v: %w", key, err)
+	}
+	return cb.b.Set(ctx, key, data, ttl)
+}
+
+func (cb codecBackend[K, V]) Delete(ctx context.Context, key K) error {
+	return cb.b.Delete(ctx, key)
+}
@@ -0,0 +1,64 @@
+package lazy
+
+import (
+	"context"
+	"testing"
+)
+
+type codecValue struct {
+	Name  string
+	Count int
+}
+
+func TestCodecs(t *testing.T) {
+	for _, c := range []Codec{GobCodec{}, JSONCodec{}} {
+		want := codecValue{"a", 1}
+		data, err := c.Marshal(want)
+		if err != nil {
+			t.Fatalf("%T: Marshal: %v", c, err)
+		}
+		var got codecValue
+		if err := c.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%T: Unmarshal: %v", c, err)
+		}
+		if got != want {
+			t.Fatalf("%T: got %+v, want %+v", c, got, want)
+		}
+	}
+}
+
+func TestNewCodecBackend(t *testing.T) {
+	b := newMemBackend[string, []byte]()
+	b.values["corrupt"] = []byte("{")
+
+	var reported []error
+	m := NewMapWithOptions(func(ctx context.Context, key string) (any, error) {
+		if key == "func" {
+			return func() {}, nil
+		}
+		return key, nil
+	}, MapOptions[string, any]{
+		Backend:        NewCodecBackend[string, any](b, JSONCodec{}),
+		OnBackendError: func(err error) { reported = append(reported, err) },
+	})
+
+	ctx := context.Background()
+	m.Get(ctx, "a")
+	if got := string(b.values["a"]); got != `"a"` {
+		t.Fatalf("backend holds %s, want %q", got, `"a"`)
+	}
+
+	// Values that cannot be encoded or decoded are reported and skipped.
+	if v, err := m.Get(ctx, "func"); err != nil || v == nil {
+		t.Fatalf("got (%v, %v), want a func", v, err)
+	}
+	if v, err := m.Get(ctx, "corrupt"); err != nil || v != "corrupt" {
+		t.Fatalf("got (%v, %v), want (corrupt, nil)", v, err)
+	}
+	if len(reported) != 2 {
+		t.Fatalf("got reported errors %v, want 2", reported)
+	}
+	if _, ok := b.values["func"]; ok {
+		t.Fatal("backend holds a value that cannot be encoded")
+	}
+}
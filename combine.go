@@ -0,0 +1,97 @@
+package lazy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Then returns a Lazy whose value is f applied to the value of l, so that a
+// lazy derived from another one shares its initialization and caches the
+// result of f in turn. Getting the returned Lazy gets l first; if l fails, so
+// does the returned Lazy, and both retry on the next Get. The value is derived
+// once: invalidating l or setting its value does not affect the returned Lazy,
+// which must be invalidated on its own to derive the value again.
+func Then[A, B any](l *Lazy[A], f func(A) B, opts ...Option) *Lazy[B] {
+	return AndThen(l, func(_ context.Context, a A) (B, error) {
+		return f(a), nil
+	}, opts...)
+}
+
+// AndThen is like Then for a function that may fail or needs a context, as for
+// a step of a small chain of dependent lazies: the returned Lazy gets l, then
+// calls f with its value. An error of either step fails the initialization of
+// the returned Lazy, which is retried on the next Get according to opts; l is
+// only initialized again if it failed, since it caches its value.
+func AndThen[A, B any](l *Lazy[A], f func(context.Context, A) (B, error), opts ...Option) *Lazy[B] {
+	return New(func(ctx context.Context) (B, error) {
+		a, err := l.Get(ctx)
+		if err != nil {
+			var zero B
+			return zero, err
+		}
+		return f(ctx, a)
+	}, opts...)
+}
+
+// MapErr returns a Lazy with the value of l whose initialization fails with
+// f applied to the error of l, if l fails, so that all callers see errors
+// translated the same way, for instance wrapped in a domain error. Both lazies
+// retry on the next Get.
+func MapErr[T any](l *Lazy[T], f func(error) error, opts ...Option) *Lazy[T] {
+	return Catch(l, func(err error) (T, error) {
+		var zero T
+		return zero, f(err)
+	}, opts...)
+}
+
+// Catch returns a Lazy with the value of l, or, if l fails, the outcome of f
+// called with its error, so that specific failures can be recovered from with
+// a replacement value for all callers. A value returned by f is cached like
+// any other; to serve a value only until l succeeds, use Fallback instead.
+// If f returns an error, the returned Lazy fails with it and both lazies retry
+// on the next Get.
+func Catch[T any](l *Lazy[T], f func(error) (T, error), opts ...Option) *Lazy[T] {
+	return New(func(ctx context.Context) (T, error) {
+		v, err := l.Get(ctx)
+		if err != nil {
+			return f(err)
+		}
+		return v, nil
+	}, opts...)
+}
+
+// WithTimeout returns a function that gets the value of l, giving up after d,
+// so that callers need not derive a context with a deadline for each call. If
+// d passes, including the time spent waiting for another caller's
+// initialization, the function returns context.DeadlineExceeded, or the cause
+// of ctx if it is done first, while the initialization continues in the
+// background, detached from the cancellation of ctx, to cache the value for
+// later calls. Only one such initialization runs at a time: the calls made
+// while it is in progress wait for it rather than starting their own.
+func WithTimeout[T any](l *Lazy[T], d time.Duration) func(context.Context) (T, error) {
+	var mu sync.Mutex
+	var run *Future[T] // the detached Get in progress, if any
+	return func(ctx context.Context) (T, error) {
+		if v, ok := l.peek(); ok {
+			return v, nil
+		}
+		mu.Lock()
+		f := run
+		if f == nil {
+			f = NewFuture(l.Get)
+			f.OnDone(func(T, error) {
+				mu.Lock()
+				run = nil
+				mu.Unlock()
+			})
+			run = f
+			f.Start(context.WithoutCancel(ctx))
+		}
+		mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		return f.Await(ctx)
+	}
+}
@@ -0,0 +1,161 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestThen(t *testing.T) {
+	errFail := errors.New("fail")
+	calls := 0
+	a := New(func(ctx context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, errFail
+		}
+		return 42, nil
+	})
+	derived := 0
+	b := Then(a, func(v int) string {
+		derived++
+		return strconv.Itoa(v)
+	})
+
+	if _, err := b.Get(context.Background()); !errors.Is(err, errFail) {
+		t.Fatalf("got error %v, want %v", err, errFail)
+	}
+	for range 2 {
+		if v, err := b.Get(context.Background()); err != nil || v != "42" {
+			t.Fatalf("got %q, %v, want 42, nil", v, err)
+		}
+	}
+	if v, _ := a.Get(context.Background()); v != 42 || calls != 2 {
+		t.Fatalf("got %d after %d calls, want 42 after 2", v, calls)
+	}
+	if derived != 1 {
+		t.Fatalf("derived %d times, want 1", derived)
+	}
+}
+
+func TestAndThen(t *testing.T) {
+	errFail := errors.New("fail")
+	loads := 0
+	a := New(func(ctx context.Context) (int, error) {
+		loads++
+		return 2, nil
+	})
+	calls := 0
+	b := AndThen(a, func(ctx context.Context, v int) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, errFail
+		}
+		return v * 10, nil
+	})
+
+	if _, err := b.Get(context.Background()); !errors.Is(err, errFail) {
+		t.Fatalf("got error %v, want %v", err, errFail)
+	}
+	if v, err := b.Get(context.Background()); err != nil || v != 20 {
+		t.Fatalf("got %v, %v, want 20, nil", v, err)
+	}
+	if loads != 1 || calls != 2 {
+		t.Fatalf("got %d loads and %d calls, want 1 and 2", loads, calls)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		l := New(func(ctx context.Context) (int, error) {
+			select {
+			case <-time.After(2 * time.Second):
+				return 42, nil
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			}
+		})
+		get := WithTimeout(l, time.Second)
+
+		if _, err := get(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if v, err := get(context.Background()); err != nil || v != 42 {
+			t.Fatalf("got %v, %v, want the detached initialization to finish", v, err)
+		}
+	})
+}
+
+func TestWithTimeout_HungInitialization(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		l := New(func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			<-release
+			return 42, nil
+		})
+		get := WithTimeout(l, time.Millisecond)
+
+		before := runtime.NumGoroutine()
+		for range 200 {
+			if _, err := get(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+			}
+		}
+		synctest.Wait()
+		if n := runtime.NumGoroutine() - before; n > 1 {
+			t.Fatalf("got %d more goroutines after timed out calls, want at most 1", n)
+		}
+
+		close(release)
+		synctest.Wait()
+		if v, err := get(context.Background()); err != nil || v != 42 {
+			t.Fatalf("got %v, %v, want 42", v, err)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("initialization called %d times, want 1", got)
+		}
+	})
+}
+
+func TestMapErr(t *testing.T) {
+	errFail := errors.New("fail")
+	errDomain := errors.New("config unavailable")
+	l := New(func(ctx context.Context) (int, error) { return 0, errFail })
+	m := MapErr(l, func(err error) error { return fmt.Errorf("%w: %w", errDomain, err) })
+
+	_, err := m.Get(context.Background())
+	if !errors.Is(err, errDomain) || !errors.Is(err, errFail) {
+		t.Fatalf("got error %v, want it translated", err)
+	}
+}
+
+func TestCatch(t *testing.T) {
+	errNotFound := errors.New("not found")
+	errFail := errors.New("fail")
+	loadErr := errFail
+	l := New(func(ctx context.Context) ([]string, error) { return nil, loadErr })
+	c := Catch(l, func(err error) ([]string, error) {
+		if errors.Is(err, errNotFound) {
+			return []string{}, nil
+		}
+		return nil, err
+	})
+
+	if _, err := c.Get(context.Background()); !errors.Is(err, errFail) {
+		t.Fatalf("got error %v, want %v", err, errFail)
+	}
+	loadErr = errNotFound
+	if v, err := c.Get(context.Background()); err != nil || v == nil {
+		t.Fatalf("got %v, %v, want the recovery value", v, err)
+	}
+}
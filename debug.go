@@ -0,0 +1,119 @@
+package lazy
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"text/tabwriter"
+	"time"
+)
+
+// Info is a snapshot of the state of a Lazy or a Map, for diagnostics and
+// metrics. It is returned by their Info methods.
+type Info struct {
+	// Type is the type of the value.
+	Type  reflect.Type
+	State State
+
+	// Stats are the counters of the Lazy or Map. Only a Map counts hits,
+	// misses and evictions.
+	Stats MapStats
+
+	// LastLoad is the duration of the last load of a Lazy, or zero if it
+	// has not loaded yet or for a Map.
+	LastLoad time.Duration
+
+	// LoadTimes is the distribution of the durations of the loads.
+	LoadTimes Histogram
+
+	// WaitTimes is the distribution of the durations for which callers
+	// waited for the loads of other callers. Together with Waiters, it
+	// shows whether priming the Lazy ahead of its use would help.
+	WaitTimes Histogram
+
+	// Err is the error of the last load of a Lazy, if it failed.
+	Err error
+
+	// Waiters is the number of callers waiting for a load.
+	Waiters int
+
+	// Size is the number of values cached by a Map, or -1 for a Lazy.
+	Size int
+}
+
+// Info returns a snapshot of the state of l.
+func (l *Lazy[T]) Info() Info {
+	info := Info{
+		Type:      reflect.TypeFor[T](),
+		State:     l.State(),
+		Stats:     MapStats{Stats: l.Stats()},
+		LastLoad:  time.Duration(l.loadTime.Load()),
+		LoadTimes: l.stats.loadTimes.histogram(),
+		WaitTimes: l.stats.waitTimes.histogram(),
+		Waiters:   l.sem.waiting(),
+		Size:      -1,
+	}
+	if p := l.failure.Load(); p != nil {
+		info.Err = *p
+	}
+	return info
+}
+
+// Info returns a snapshot of the state of m.
+func (m *Map[K, V]) Info() Info {
+	info := Info{
+		Type:  reflect.TypeFor[V](),
+		State: m.State(),
+		Stats: m.Stats(),
+		Size:  m.Len(),
+	}
+	for i := range m.shards {
+		sh := &m.shards[i]
+		info.LoadTimes.add(sh.stats.lazy.loadTimes.histogram())
+		info.WaitTimes.add(sh.stats.lazy.waitTimes.histogram())
+		sh.mu.Lock()
+		for elem := sh.lru.Front(); elem != nil; elem = elem.Next() {
+			info.Waiters += elem.Value.(*mapEntry[K, V]).l.sem.waiting()
+		}
+		sh.mu.Unlock()
+	}
+	return info
+}
+
+// Handler returns an http.Handler that renders the registered lazies and maps
+// as a text table, like the handler of package expvar: their name, state,
+// value type, duration of the last load, number of loads, error of the last
+// load, number of waiting callers and, for maps, number of cached values.
+// It is meant for diagnosing initializations that are slow or stuck.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tSTATE\tTYPE\tLAST LOAD\tLOADS\tWAITERS\tSIZE\tLAST ERROR")
+		for name, h := range Registered() {
+			in, ok := h.(interface{ Info() Info })
+			if !ok {
+				fmt.Fprintf(tw, "%s\t%v\t-\t-\t-\t-\t-\t-\n", name, h.State())
+				continue
+			}
+			fmt.Fprintln(tw, in.Info().row(name))
+		}
+		tw.Flush()
+	})
+}
+
+// row formats info as a row of the table rendered by Handler.
+func (info Info) row(name string) string {
+	lastLoad, size, lastErr := "-", "-", "-"
+	if info.LastLoad > 0 {
+		lastLoad = info.LastLoad.String()
+	}
+	if info.Size >= 0 {
+		size = fmt.Sprint(info.Size)
+	}
+	if info.Err != nil {
+		lastErr = info.Err.Error()
+	}
+	return fmt.Sprintf("%s\t%v\t%v\t%s\t%d\t%d\t%s\t%s",
+		name, info.State, info.Type, lastLoad, info.Stats.Loads, info.Waiters, size, lastErr)
+}
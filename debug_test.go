@@ -0,0 +1,51 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	l := New(func(ctx context.Context) (int, error) { return 0, errors.New("dial failed") })
+	m := NewMap(func(ctx context.Context, key string) (string, error) { return key, nil })
+	Register("test.debug.lazy", l)
+	Register("test.debug.map", m)
+	defer Unregister("test.debug.lazy")
+	defer Unregister("test.debug.map")
+
+	l.Prime(context.Background())
+	m.Get(context.Background(), "a")
+	m.Get(context.Background(), "b")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/lazy", nil))
+	body := rec.Body.String()
+	for _, re := range []string{
+		`(?m)^NAME +STATE +TYPE +LAST LOAD +LOADS +WAITERS +SIZE +LAST ERROR$`,
+		`(?m)^test\.debug\.lazy +failed +int +\S+ +1 +0 +- +dial failed$`,
+		`(?m)^test\.debug\.map +ready +string +- +2 +0 +2 +-$`,
+	} {
+		if !regexp.MustCompile(re).MatchString(body) {
+			t.Errorf("body does not match %s:\n%s", re, body)
+		}
+	}
+}
+
+func TestMap_Handle(t *testing.T) {
+	m := NewMap(func(ctx context.Context, key string) (string, error) { return key, nil })
+	var h Handle = m
+	if err := h.Prime(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	m.Get(context.Background(), "a")
+	if s := h.State(); s != StateReady {
+		t.Fatalf("got state %v, want %v", s, StateReady)
+	}
+	h.Invalidate()
+	if n := m.Len(); n != 0 {
+		t.Fatalf("got %d values after Invalidate, want 0", n)
+	}
+}
@@ -0,0 +1,55 @@
+package lazy
+
+// A doneCallback is a callback registered with Lazy.OnDone.
+type doneCallback[T any] struct {
+	f func(T, error)
+
+	// gen is the generation of the last value f was called with, and
+	// called reports whether f has been called at all.
+	gen    uint64
+	called bool
+}
+
+// OnDone registers f to be called once the initialization of l completes, so
+// that event-driven code need not park a goroutine in Get. If a value is cached,
+// f is called immediately with it; otherwise it is called with the outcome of
+// the first initialization to complete, whether a value or an error. Afterwards,
+// f is called again with each new value cached by l, after Invalidate or with
+// Set, but not with later errors. f is called in the goroutine that completed
+// the initialization or called Set, or in the calling one if a value is cached,
+// and should not block.
+func (l *Lazy[T]) OnDone(f func(T, error)) {
+	cb := &doneCallback[T]{f: f}
+	l.mu.Lock()
+	p := l.value.Load()
+	if p != nil {
+		cb.gen, cb.called = l.generation.Load(), true
+	}
+	l.onDone = append(l.onDone, cb)
+	l.mu.Unlock()
+	if p != nil {
+		f(*p, nil)
+	}
+}
+
+// fireDone calls the callbacks registered with OnDone that are due: with v, of
+// generation gen, if err is nil and they have not been called with a value of
+// that generation or a later one, or else with err if they have not been
+// called yet.
+func (l *Lazy[T]) fireDone(v T, err error, gen uint64) {
+	l.mu.Lock()
+	var due []func(T, error)
+	for _, cb := range l.onDone {
+		if err == nil && cb.gen < gen || err != nil && !cb.called {
+			if err == nil {
+				cb.gen = gen
+			}
+			cb.called = true
+			due = append(due, cb.f)
+		}
+	}
+	l.mu.Unlock()
+	for _, f := range due {
+		f(v, err)
+	}
+}
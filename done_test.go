@@ -0,0 +1,62 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestLazy_OnDone(t *testing.T) {
+	errFail := errors.New("fail")
+	fail := true
+	l := New(func(ctx context.Context) (int, error) {
+		if fail {
+			return 0, errFail
+		}
+		return 1, nil
+	})
+	var values []int
+	var errs []error
+	l.OnDone(func(v int, err error) {
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		values = append(values, v)
+	})
+
+	l.Get(context.Background())
+	l.Get(context.Background())
+	fail = false
+	l.Get(context.Background())
+	l.Get(context.Background())
+	l.Set(2)
+	if len(errs) != 1 || !errors.Is(errs[0], errFail) {
+		t.Fatalf("got errors %v, want the first failure only", errs)
+	}
+	if !slices.Equal(values, []int{1, 2}) {
+		t.Fatalf("got values %v, want [1 2]", values)
+	}
+
+	var late []int
+	l.OnDone(func(v int, err error) { late = append(late, v) })
+	if !slices.Equal(late, []int{2}) {
+		t.Fatalf("got %v for a callback registered once ready, want the cached value", late)
+	}
+}
+
+func TestFuture_OnDone(t *testing.T) {
+	f := NewFuture(func(ctx context.Context) (int, error) { return 42, nil })
+	got := make(chan int, 2)
+	f.OnDone(func(v int, err error) { got <- v })
+	f.Start(context.Background())
+	if v := <-got; v != 42 {
+		t.Fatalf("got %d, want 42", v)
+	}
+	<-f.Done()
+	f.OnDone(func(v int, err error) { got <- v })
+	if v := <-got; v != 42 {
+		t.Fatalf("got %d after completion, want 42", v)
+	}
+}
@@ -0,0 +1,55 @@
+package lazy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+)
+
+// stateColors are the fill colors of the states in WriteDOT.
+var stateColors = map[State]string{
+	StateIdle:     "white",
+	StateLoading:  "lightyellow",
+	StateReady:    "palegreen",
+	StateFailed:   "lightcoral",
+	StateClosed:   "lightgray",
+	StateDegraded: "orange",
+}
+
+// WriteDOT writes the members of g and their dependencies to w as a Graphviz
+// DOT graph, with each member labeled and colored by its current state. An
+// edge points from a member to each of its dependencies. Dependencies that
+// are not in g are drawn dashed.
+func (g *Group) WriteDOT(w io.Writer) error {
+	g.mu.Lock()
+	members := g.members
+	names := maps.Clone(g.names)
+	g.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph lazy {")
+	fmt.Fprintln(bw, "\tnode [shape=box, style=filled];")
+	missing := make(map[string]bool)
+	for _, m := range members {
+		st := m.h.State()
+		fmt.Fprintf(bw, "\t%s [label=%s, fillcolor=%s];\n",
+			strconv.Quote(m.name), strconv.Quote(m.name+"\n"+st.String()), stateColors[st])
+	}
+	for _, m := range members {
+		for _, dep := range m.deps {
+			if _, ok := names[dep]; !ok && !missing[dep] {
+				missing[dep] = true
+				fmt.Fprintf(bw, "\t%s [style=dashed];\n", strconv.Quote(dep))
+			}
+		}
+	}
+	for _, m := range members {
+		for _, dep := range m.deps {
+			fmt.Fprintf(bw, "\t%s -> %s;\n", strconv.Quote(m.name), strconv.Quote(dep))
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
@@ -0,0 +1,34 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGroup_WriteDOT(t *testing.T) {
+	var g Group
+	g.Add("api", New(func(ctx context.Context) (int, error) { return 1, nil }), "db", "missing")
+	g.Add("db", New(func(ctx context.Context) (int, error) { return 0, errors.New("failed") }))
+	g.Add("config", New(func(ctx context.Context) (int, error) { return 1, nil }))
+	g.Prime(context.Background())
+
+	var b strings.Builder
+	if err := g.WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph lazy {
+	node [shape=box, style=filled];
+	"api" [label="api\nidle", fillcolor=white];
+	"db" [label="db\nfailed", fillcolor=lightcoral];
+	"config" [label="config\nready", fillcolor=palegreen];
+	"missing" [style=dashed];
+	"api" -> "db";
+	"api" -> "missing";
+}
+`
+	if got := b.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package lazy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrFrozen is wrapped by the error returned once a Lazy has stopped retrying
+// its initialization function.
+var ErrFrozen = errors.New("lazy: initialization frozen")
+
+// ErrWaitTimeout is returned when a caller gives up waiting for another
+// caller's initialization after the duration configured with WithMaxWait.
+var ErrWaitTimeout = errors.New("lazy: timed out waiting for initialization")
+
+// ErrBusy is returned instead of waiting when the number of callers waiting
+// for an initialization has reached the limit configured with WithMaxWaiters,
+// and by TryGet when another goroutine is initializing the value.
+var ErrBusy = errors.New("lazy: initialization in progress")
+
+// ErrReentrant is wrapped by the error returned when an initialization
+// function calls back into the Lazy it is initializing.
+var ErrReentrant = errors.New("lazy: reentrant initialization")
+
+// ErrMissing is wrapped by the error returned for a key that is absent from the
+// result of a batch load function.
+var ErrMissing = errors.New("lazy: no value for key in batch result")
+
+// ErrClosed is returned by a Lazy or Map that has been closed.
+var ErrClosed = errors.New("lazy: closed")
+
+// ErrNotReady is wrapped by the error returned by Check when a value is not
+// ready to be used.
+var ErrNotReady = errors.New("lazy: not ready")
+
+// ErrAbandoned is wrapped by the cancellation cause of an initialization
+// running with WithMergedContext once every interested caller has gone away.
+var ErrAbandoned = errors.New("lazy: initialization abandoned by all callers")
+
+// An Error is returned by a Lazy configured with WithName or WithCallSite when
+// its initialization fails with Err, identifying the Lazy that failed.
+type Error struct {
+	Name string // set with WithName, if any
+	Site string // where WithCallSite was called, as "file:line", if at all
+	Err  error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("lazy")
+	if e.Name != "" {
+		fmt.Fprintf(&b, " %q", e.Name)
+	}
+	if e.Site != "" {
+		fmt.Fprintf(&b, " (created at %s)", e.Site)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// A PanicError is returned when the initialization function panics and panic
+// recovery is enabled with WithPanicRecovery.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("lazy: initialization panicked: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// A RetryHint is an error that indicates how long to wait before retrying the
+// operation that failed with it, such as a rate-limited response carrying a
+// Retry-After header. When an initialization fails with an error wrapping a
+// RetryHint, the Lazy does not call the initialization function again before
+// the delay has elapsed, in addition to any delay set with WithBackoff.
+type RetryHint interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// A RetryLaterError is returned to callers of a Lazy whose previous
+// initialization failed with Err while the retry delay, due to WithBackoff or
+// a RetryHint, has not elapsed. It is itself a RetryHint reporting the
+// remaining delay.
+type RetryLaterError struct {
+	Err  error
+	Wait time.Duration
+}
+
+func (e *RetryLaterError) Error() string {
+	return fmt.Sprintf("lazy: retry in %v: %v", e.Wait, e.Err)
+}
+
+func (e *RetryLaterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns the remaining delay before the initialization may be
+// retried.
+func (e *RetryLaterError) RetryAfter() time.Duration {
+	return e.Wait
+}
@@ -0,0 +1,47 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestWithName_Error(t *testing.T) {
+	errDial := errors.New("dial failed")
+	l := New(func(ctx context.Context) (int, error) { return 0, errDial }, WithName("db-pool"))
+
+	_, err := l.Get(context.Background())
+	if want := `lazy "db-pool": dial failed`; err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %s", err, want)
+	}
+	var lerr *Error
+	if !errors.As(err, &lerr) || lerr.Name != "db-pool" || !errors.Is(err, errDial) {
+		t.Fatalf("got error %#v, want an *Error named db-pool wrapping %v", err, errDial)
+	}
+}
+
+func TestWithCallSite(t *testing.T) {
+	l := New(func(ctx context.Context) (int, error) {
+		return 0, errors.New("failed")
+	}, WithName("db"), WithCallSite())
+
+	_, err := l.Get(context.Background())
+	re := regexp.MustCompile(`^lazy "db" \(created at errors_test\.go:\d+\): failed$`)
+	if err == nil || !re.MatchString(err.Error()) {
+		t.Fatalf("got error %v, want one matching %s", err, re)
+	}
+}
+
+func TestWithName_FrozenError(t *testing.T) {
+	l := New(func(ctx context.Context) (int, error) {
+		return 0, errors.New("failed")
+	}, WithName("db"), WithMaxAttempts(1))
+
+	l.Get(context.Background())
+	_, err := l.Get(context.Background())
+	var lerr *Error
+	if !errors.As(err, &lerr) || !errors.Is(err, ErrFrozen) {
+		t.Fatalf("got error %v, want an *Error wrapping %v", err, ErrFrozen)
+	}
+}
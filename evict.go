@@ -0,0 +1,89 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+)
+
+// An EvictReason describes why a value was removed from a Map.
+type EvictReason int
+
+const (
+	// EvictCapacity means the value was evicted to keep the Map within the
+	// limits set by WithCapacity or MapOptions.MaxCost.
+	EvictCapacity EvictReason = iota + 1
+
+	// EvictExpired means the value expired and was replaced by a reload.
+	EvictExpired
+
+	// EvictForgotten means the value was removed by Forget or ForgetAll.
+	EvictForgotten
+
+	// EvictInvalidated means the value was removed by InvalidateTag.
+	EvictInvalidated
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictExpired:
+		return "expired"
+	case EvictForgotten:
+		return "forgotten"
+	case EvictInvalidated:
+		return "invalidated"
+	}
+	return fmt.Sprintf("EvictReason(%d)", int(r))
+}
+
+// An eviction is a value removed from a Map that has yet to be reported.
+type eviction[K, V any] struct {
+	key     K
+	value   V
+	cleanup func(context.Context) error
+	reason  EvictReason
+}
+
+// unlock releases sh.mu, reports the values removed while it was held to the
+// OnEvict callback and then calls their cleanup functions.
+func (m *Map[K, V]) unlock(sh *mapShard[K, V]) {
+	evicted := sh.evicted
+	sh.evicted = nil
+	sh.mu.Unlock()
+	for _, ev := range evicted {
+		if m.mo.OnEvict != nil {
+			m.mo.OnEvict(ev.key, ev.value, ev.reason)
+		}
+		runCleanup(ev.cleanup)
+	}
+}
+
+// evictedLocked records that the value s of e was removed for reason. It must
+// be called with sh.mu held.
+func (sh *mapShard[K, V]) evictedLocked(e *mapEntry[K, V], s stamped[V], reason EvictReason) {
+	if sh.track || s.cleanup != nil {
+		sh.evicted = append(sh.evicted, eviction[K, V]{e.key, s.value, s.cleanup, reason})
+	}
+}
+
+// dropStaleLocked drops the stale value of e, if any, which has been replaced
+// by a reload. It must be called with sh.mu held.
+func (sh *mapShard[K, V]) dropStaleLocked(e *mapEntry[K, V]) {
+	if e.stale != nil {
+		sh.evictedLocked(e, *e.stale, EvictExpired)
+		e.stale = nil
+	}
+}
+
+// dropValuesLocked records the removal of the values of e for reason. It must
+// be called with sh.mu held.
+func (sh *mapShard[K, V]) dropValuesLocked(e *mapEntry[K, V], reason EvictReason) {
+	if e.stale != nil {
+		sh.evictedLocked(e, *e.stale, reason)
+		e.stale = nil
+	}
+	if s, ok := e.l.peek(); ok {
+		sh.evictedLocked(e, s, reason)
+	}
+}
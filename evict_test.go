@@ -0,0 +1,74 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// evictLog records the calls of an OnEvict callback.
+type evictLog struct {
+	events []string
+}
+
+func (l *evictLog) record(key string, value int, reason EvictReason) {
+	l.events = append(l.events, fmt.Sprintf("%s=%d %v", key, value, reason))
+}
+
+func TestMapOptions_OnEvict(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var log evictLog
+		var m *Map[string, int]
+		m = NewMapWithOptions(func(ctx context.Context, key string) (int, error) {
+			return len(key), nil
+		}, MapOptions[string, int]{
+			OnEvict: func(key string, value int, reason EvictReason) {
+				// The callback runs without holding the Map's locks.
+				m.Forget("unrelated")
+				log.record(key, value, reason)
+			},
+		}, WithCapacity(2), WithTTL(time.Minute))
+
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		m.Get(ctx, "bb")
+		m.Get(ctx, "ccc") // Evicts "a".
+		m.Forget("bb")
+		time.Sleep(time.Minute)
+		m.Get(ctx, "ccc") // Reloads the expired value.
+		m.ForgetAll()
+
+		want := []string{"a=1 capacity", "bb=2 forgotten", "ccc=3 expired", "ccc=3 forgotten"}
+		if !slices.Equal(log.events, want) {
+			t.Fatalf("got evictions %q, want %q", log.events, want)
+		}
+	})
+}
+
+func TestMapOptions_OnEvictStaleValue(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var log evictLog
+		var calls int
+		m := NewMapWithOptions(func(ctx context.Context, key string) (int, error) {
+			calls++
+			return calls, nil
+		}, MapOptions[string, int]{OnEvict: log.record}, WithTTL(time.Minute), WithStaleWhileRevalidate(time.Minute))
+
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		time.Sleep(time.Minute)
+		if v, _ := m.Get(ctx, "a"); v != 1 {
+			t.Fatalf("got %d, want stale value 1", v)
+		}
+		synctest.Wait()
+
+		// The stale value is reported once it has been replaced.
+		want := []string{"a=1 expired"}
+		if !slices.Equal(log.events, want) {
+			t.Fatalf("got evictions %q, want %q", log.events, want)
+		}
+	})
+}
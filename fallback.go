@@ -0,0 +1,36 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+)
+
+// Fallback returns a Lazy with the value of l that serves v instead of failing,
+// so that a non-critical feature degrades gracefully when its value cannot be
+// initialized. Get of the returned Lazy returns v, with a nil error, whenever
+// getting l fails with an error for which match returns true, or with any error
+// if match is nil, except ErrClosed and the errors of callers whose own context
+// is done. To serve v only once the retry policy of l has given up, match
+// errors with errors.Is(err, ErrFrozen).
+//
+// The fallback value is not cached, so the next Get tries to get l again, and
+// State reports StateDegraded until a value is cached. TryGet is not affected.
+// The returned Lazy is created by New with opts.
+func Fallback[T any](l *Lazy[T], v T, match func(error) bool, opts ...Option) *Lazy[T] {
+	fl := New(l.Get, opts...)
+	fl.fallback = &v
+	fl.fallbackMatch = match
+	return fl
+}
+
+// degrade returns the fallback value of l in place of err, returned by Get with
+// ctx, if it applies to err, and otherwise err. It does not apply if ctx is
+// done, since the failure is then the caller's rather than the initialization's.
+func (l *Lazy[T]) degrade(ctx context.Context, err error) (T, error) {
+	if errors.Is(err, ErrClosed) || ctx.Err() != nil || l.fallbackMatch != nil && !l.fallbackMatch(err) {
+		var zero T
+		return zero, err
+	}
+	l.degraded.Store(true)
+	return *l.fallback, nil
+}
@@ -0,0 +1,72 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestFallback(t *testing.T) {
+	errFail := errors.New("fail")
+	fail := true
+	l := New(func(ctx context.Context) (int64, error) {
+		if fail {
+			return 0, errFail
+		}
+		return 1, nil
+	})
+	fl := Fallback(l, 0, nil)
+
+	for range 2 {
+		if v, err := fl.Get(context.Background()); err != nil || v != 0 {
+			t.Fatalf("got %v, %v, want the fallback value", v, err)
+		}
+	}
+	if fl.State() != StateDegraded {
+		t.Fatalf("got state %v, want %v", fl.State(), StateDegraded)
+	}
+
+	fail = false
+	if v, err := fl.Get(context.Background()); err != nil || v != 1 {
+		t.Fatalf("got %v, %v after recovery, want 1, nil", v, err)
+	}
+	if fl.State() != StateReady {
+		t.Fatalf("got state %v after recovery, want %v", fl.State(), StateReady)
+	}
+}
+
+func TestFallback_Match(t *testing.T) {
+	errFail := errors.New("fail")
+	l := New(func(ctx context.Context) (string, error) {
+		return "", errFail
+	}, WithMaxAttempts(2))
+	fl := Fallback(l, "default", func(err error) bool { return errors.Is(err, ErrFrozen) })
+
+	if _, err := fl.Get(context.Background()); !errors.Is(err, errFail) {
+		t.Fatalf("got error %v before retries are exhausted, want %v", err, errFail)
+	}
+	if v, err := fl.Get(context.Background()); err != nil || v != "default" {
+		t.Fatalf("got %q, %v once frozen, want default, nil", v, err)
+	}
+}
+
+func TestFallback_CallerContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		l := New(func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+		fl := Fallback(l, -1, nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := fl.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+		if fl.State() == StateDegraded {
+			t.Fatal("degraded by the deadline of the caller")
+		}
+	})
+}
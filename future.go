@@ -0,0 +1,98 @@
+package lazy
+
+import (
+	"context"
+	"sync"
+)
+
+// A Future is a value computed once in the background, for the pattern of
+// starting an expensive computation early and using its result later. Unlike a
+// Lazy, which is initialized on demand and retries failed initializations, a
+// Future runs its function once, when started, and keeps its outcome, whether
+// a value or an error. A Future is safe for concurrent use by multiple
+// goroutines.
+type Future[T any] struct {
+	fn    func(context.Context) (T, error)
+	start sync.Once
+	done  chan struct{}
+	value T
+	err   error
+
+	// mu guards onDone, the callbacks registered with OnDone, and the
+	// closing of done.
+	mu     sync.Mutex
+	onDone []func(T, error)
+}
+
+// NewFuture returns a Future computed by f, which is not called until the
+// Future is started.
+func NewFuture[T any](f func(context.Context) (T, error)) *Future[T] {
+	return &Future[T]{fn: f, done: make(chan struct{})}
+}
+
+// Start calls the function of f in a new goroutine with ctx, unless f has
+// already been started, and returns immediately. The computation is cancelled
+// with ctx; use context.WithoutCancel to keep it running past the return of
+// the caller.
+func (f *Future[T]) Start(ctx context.Context) {
+	f.start.Do(func() {
+		go func() {
+			defer f.finish()
+			f.value, f.err = f.fn(ctx)
+		}()
+	})
+}
+
+// Await waits for the outcome of f and returns it, starting f if it has not
+// been started yet, with ctx but without its cancellation. If ctx is done
+// first, Await returns context.Cause(ctx) without affecting the computation,
+// so that a later call may still receive its outcome.
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	f.Start(context.WithoutCancel(ctx))
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, context.Cause(ctx)
+	}
+}
+
+// finish closes the done channel of f and calls its callbacks.
+func (f *Future[T]) finish() {
+	f.mu.Lock()
+	close(f.done)
+	onDone := f.onDone
+	f.onDone = nil
+	f.mu.Unlock()
+	for _, cb := range onDone {
+		cb(f.value, f.err)
+	}
+}
+
+// OnDone registers cb to be called with the outcome of f once it is available,
+// or immediately if it already is. It does not start f. cb is called in the
+// goroutine running f, or in the calling one if f is done, and should not
+// block.
+func (f *Future[T]) OnDone(cb func(T, error)) {
+	f.mu.Lock()
+	select {
+	case <-f.done:
+		f.mu.Unlock()
+		cb(f.value, f.err)
+	default:
+		f.onDone = append(f.onDone, cb)
+		f.mu.Unlock()
+	}
+}
+
+// Done returns a channel that is closed once the outcome of f is available.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Prime is like Await but discards the value. It makes a Future a Primer.
+func (f *Future[T]) Prime(ctx context.Context) error {
+	_, err := f.Await(ctx)
+	return err
+}
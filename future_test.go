@@ -0,0 +1,86 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestFuture(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		calls := 0
+		f := NewFuture(func(ctx context.Context) (int, error) {
+			calls++
+			time.Sleep(time.Second)
+			return 42, nil
+		})
+		synctest.Wait()
+		if calls != 0 {
+			t.Fatal("function called before Start")
+		}
+
+		start := time.Now()
+		f.Start(context.Background())
+		f.Start(context.Background())
+		time.Sleep(time.Second)
+		for range 2 {
+			v, err := f.Await(context.Background())
+			if err != nil || v != 42 {
+				t.Fatalf("got %v, %v, want 42, nil", v, err)
+			}
+		}
+		if d := time.Since(start); d != time.Second {
+			t.Fatalf("took %v, want the computation to overlap the caller", d)
+		}
+		if calls != 1 {
+			t.Fatalf("got %d calls, want 1", calls)
+		}
+	})
+}
+
+func TestFuture_AwaitCancel(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errFail := errors.New("fail")
+		proceed := make(chan struct{})
+		f := NewFuture(func(ctx context.Context) (int, error) {
+			<-proceed
+			return 0, errFail
+		})
+		f.Start(context.Background())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := f.Await(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("got error %v, want %v", err, context.Canceled)
+		}
+		close(proceed)
+		<-f.Done()
+		if _, err := f.Await(context.Background()); !errors.Is(err, errFail) {
+			t.Fatalf("got error %v, want %v", err, errFail)
+		}
+	})
+}
+
+func TestFuture_AwaitTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		f := NewFuture(func(ctx context.Context) (int, error) {
+			select {
+			case <-time.After(2 * time.Second):
+				return 42, nil
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			}
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, err := f.Await(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+		if v, err := f.Await(context.Background()); err != nil || v != 42 {
+			t.Fatalf("got %v, %v after a timed out Await, want 42, nil", v, err)
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package lazy
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// A Group is a set of named lazies, possibly of different types, that are
+// initialized together, as at the startup of a service. The zero value is an
+// empty Group ready to use. A Group is safe for concurrent use by multiple
+// goroutines.
+type Group struct {
+	mu      sync.Mutex
+	members []groupMember
+	names   map[string]int // index of each member
+	limit   int            // zero if unlimited
+	report  Report         // of the last Prime
+}
+
+// A groupMember is a Handle added to a Group.
+type groupMember struct {
+	name string
+	h    Handle
+	deps []string
+}
+
+// Add adds h, typically a Lazy, to g under name. If deps are given, Prime
+// initializes h only once the members of g with those names have been
+// initialized successfully. They may be added before or after h. Add returns an
+// error if name is already in g or if the dependencies would form a cycle.
+func (g *Group) Add(name string, h Handle, deps ...string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.names[name]; ok {
+		return fmt.Errorf("lazy: %q added to group twice", name)
+	}
+	if g.names == nil {
+		g.names = make(map[string]int)
+	}
+	g.names[name] = len(g.members)
+	g.members = append(g.members, groupMember{name, h, deps})
+	if path := g.cycleLocked(name); path != nil {
+		g.members = g.members[:len(g.members)-1]
+		delete(g.names, name)
+		return fmt.Errorf("lazy: dependency cycle: %s", strings.Join(path, " -> "))
+	}
+	return nil
+}
+
+// cycleLocked returns the path of a dependency cycle through name, starting and
+// ending with it, or nil if there is none. It must be called with g.mu held.
+func (g *Group) cycleLocked(name string) []string {
+	visited := make(map[string]bool)
+	var visit func(path []string) []string
+	visit = func(path []string) []string {
+		i, ok := g.names[path[len(path)-1]]
+		if !ok {
+			return nil
+		}
+		for _, dep := range g.members[i].deps {
+			if dep == name {
+				return append(path, dep)
+			}
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			if p := visit(append(path, dep)); p != nil {
+				return p
+			}
+		}
+		return nil
+	}
+	return visit([]string{name})
+}
+
+// SetLimit limits the number of initializations that Prime runs at once to n,
+// so that priming many lazies does not overwhelm their dependencies. A value
+// of zero or less means no limit, which is the default. It applies to calls of
+// Prime made after it returns.
+func (g *Group) SetLimit(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.limit = max(n, 0)
+}
+
+// Prime initializes all the lazies of g and waits for them. Each is initialized
+// as soon as its dependencies are, so that independent lazies are initialized
+// concurrently, within the limit set with SetLimit. A lazy whose dependency
+// failed or is not in g is not initialized. Prime returns the errors of the
+// lazies that failed or were not initialized, each as a *MemberError, joined
+// with errors.Join, or nil if all succeeded. A failure does not stop the
+// initialization of the members that do not depend on the failed one, so that
+// the error and the Report give the full picture.
+func (g *Group) Prime(ctx context.Context) error {
+	g.mu.Lock()
+	members := g.members
+	names := maps.Clone(g.names)
+	limit := g.limit
+	g.mu.Unlock()
+
+	var sem chan struct{}
+	if limit > 0 {
+		sem = make(chan struct{}, limit)
+	}
+	errs := make([]error, len(members))
+	report := make(Report, len(members))
+	done := make([]chan struct{}, len(members))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	var wg sync.WaitGroup
+	for i, m := range members {
+		wg.Go(func() {
+			defer close(done[i])
+			report[i].Name = m.name
+			if err := waitDeps(ctx, m, names, done, errs); err != nil {
+				report[i].Err = err
+				errs[i] = &MemberError{Name: m.name, Err: err}
+				return
+			}
+			if sem != nil {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
+			report[i] = prime(ctx, m)
+			if err := report[i].Err; err != nil {
+				errs[i] = &MemberError{Name: m.name, Err: err}
+			}
+		})
+	}
+	wg.Wait()
+
+	g.mu.Lock()
+	g.report = report
+	g.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// A MemberError is the error of a member of a Group, returned joined with the
+// errors of the other members by the methods of Group.
+type MemberError struct {
+	Name string
+	Err  error
+}
+
+func (e *MemberError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *MemberError) Unwrap() error {
+	return e.Err
+}
+
+// waitDeps waits for the dependencies of m to be primed, given the done
+// channels and errors of all members, and returns an error if one of them
+// failed, is unknown, or ctx is done first.
+func waitDeps(ctx context.Context, m groupMember, names map[string]int, done []chan struct{}, errs []error) error {
+	for _, dep := range m.deps {
+		i, ok := names[dep]
+		if !ok {
+			return fmt.Errorf("lazy: unknown dependency %q", dep)
+		}
+		select {
+		case <-done[i]:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+		if errs[i] != nil {
+			return fmt.Errorf("lazy: dependency %q failed", dep)
+		}
+	}
+	return nil
+}
+
+// prime primes m and returns its timing.
+func prime(ctx context.Context, m groupMember) Timing {
+	st, counted := m.h.(interface{ Stats() Stats })
+	var loads uint64
+	if counted {
+		loads = st.Stats().Loads
+	}
+	t := Timing{Name: m.name, Start: time.Now()}
+	t.Err = m.h.Prime(ctx)
+	t.Duration = time.Since(t.Start)
+	if counted {
+		t.Attempts = int(st.Stats().Loads - loads)
+	}
+	return t
+}
+
+// A Timing describes the initialization of a member of a Group by Prime.
+type Timing struct {
+	Name     string
+	Start    time.Time // zero if it was not initialized
+	Duration time.Duration
+
+	// Attempts is the number of calls of the initialization function, or
+	// zero if it was already initialized or the member does not report
+	// its Stats.
+	Attempts int
+
+	// Err is the error of the initialization, if it failed or was not
+	// attempted.
+	Err error
+}
+
+// A Report holds the timings of the members of a Group.
+type Report []Timing
+
+// Report returns the timings of the members of g during the last call of
+// Prime, in the order they were added, or nil if Prime has not been called.
+func (g *Group) Report() Report {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.report)
+}
+
+// Succeeded returns the names of the members of r that were initialized
+// successfully, in the order of r.
+func (r Report) Succeeded() []string {
+	var names []string
+	for _, t := range r {
+		if t.Err == nil {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+// Failed returns the timings of the members of r that failed or were not
+// initialized, in the order of r.
+func (r Report) Failed() Report {
+	var failed Report
+	for _, t := range r {
+		if t.Err != nil {
+			failed = append(failed, t)
+		}
+	}
+	return failed
+}
+
+// String formats r as a table for logs, slowest member first.
+func (r Report) String() string {
+	sorted := slices.Clone(r)
+	slices.SortStableFunc(sorted, func(a, b Timing) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tDURATION\tATTEMPTS\tOUTCOME")
+	for _, t := range sorted {
+		outcome := "ok"
+		if t.Err != nil {
+			outcome = t.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%d\t%s\n", t.Name, t.Duration, t.Attempts, outcome)
+	}
+	tw.Flush()
+	return b.String()
+}
@@ -0,0 +1,211 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestGroup_Prime(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errDB := errors.New("db down")
+		db := New(func(ctx context.Context) (string, error) {
+			time.Sleep(time.Second)
+			return "", errDB
+		})
+		cfg := New(func(ctx context.Context) (map[string]string, error) {
+			time.Sleep(time.Second)
+			return map[string]string{}, nil
+		})
+		port := New(func(ctx context.Context) (int, error) {
+			time.Sleep(time.Second)
+			return 8080, nil
+		})
+
+		var g Group
+		g.Add("db", db)
+		g.Add("config", cfg)
+		g.Add("port", port)
+		if err := g.Add("db", db); err == nil {
+			t.Fatal("Add succeeded for a duplicate name")
+		}
+
+		start := time.Now()
+		err := g.Prime(context.Background())
+		if d := time.Since(start); d != time.Second {
+			t.Fatalf("Prime took %v, want %v", d, time.Second)
+		}
+		if !errors.Is(err, errDB) || err.Error() != "db: db down" {
+			t.Fatalf("got error %v, want %q", err, "db: db down")
+		}
+		if cfg.State() != StateReady || port.State() != StateReady {
+			t.Fatal("Prime did not initialize all lazies")
+		}
+	})
+}
+
+func TestGroup_SetLimit(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var g Group
+		for i := range 5 {
+			g.Add(fmt.Sprint(i), New(func(ctx context.Context) (int, error) {
+				time.Sleep(time.Second)
+				return i, nil
+			}))
+		}
+		g.SetLimit(2)
+
+		start := time.Now()
+		if err := g.Prime(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d != 3*time.Second {
+			t.Fatalf("Prime took %v, want %v", d, 3*time.Second)
+		}
+	})
+}
+
+func TestGroup_Dependencies(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mu sync.Mutex
+		var order []string
+		member := func(name string, err error) *Lazy[string] {
+			return New(func(ctx context.Context) (string, error) {
+				time.Sleep(time.Second)
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				return name, err
+			})
+		}
+
+		var g Group
+		// Dependencies may be added after their dependents.
+		g.Add("api", member("api", nil), "db", "cache")
+		g.Add("db", member("db", nil), "config")
+		g.Add("cache", member("cache", nil), "config")
+		g.Add("config", member("config", nil))
+		g.Add("broken", member("broken", errors.New("failed")))
+		g.Add("worker", member("worker", nil), "broken")
+		g.Add("orphan", member("orphan", nil), "missing")
+
+		start := time.Now()
+		err := g.Prime(context.Background())
+		// config, then db and cache together, then api.
+		if d := time.Since(start); d != 3*time.Second {
+			t.Fatalf("Prime took %v, want %v", d, 3*time.Second)
+		}
+		if order[0] != "config" && order[0] != "broken" || order[len(order)-1] != "api" {
+			t.Fatalf("got initialization order %q", order)
+		}
+		for _, want := range []string{"broken: failed", `worker: lazy: dependency "broken" failed`, `orphan: lazy: unknown dependency "missing"`} {
+			if !strings.Contains(err.Error(), want) {
+				t.Fatalf("got error %q, want it to contain %q", err, want)
+			}
+		}
+		if slices.Contains(order, "worker") || slices.Contains(order, "orphan") {
+			t.Fatalf("initialized lazies whose dependencies failed: %q", order)
+		}
+	})
+}
+
+func TestGroup_Cycle(t *testing.T) {
+	l := New(func(ctx context.Context) (int, error) { return 1, nil })
+	var g Group
+	if err := g.Add("a", l, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Add("b", l, "c"); err != nil {
+		t.Fatal(err)
+	}
+	err := g.Add("c", l, "a")
+	if err == nil || err.Error() != "lazy: dependency cycle: c -> a -> b -> c" {
+		t.Fatalf("got error %v, want a cycle c -> a -> b -> c", err)
+	}
+	if err := g.Add("self", l, "self"); err == nil {
+		t.Fatal("Add succeeded for a lazy depending on itself")
+	}
+	// The rejected lazy was not added.
+	if err := g.Add("c", l); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGroup_Report(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var g Group
+		g.Add("slow", New(func(ctx context.Context) (int, error) {
+			time.Sleep(2 * time.Second)
+			return 1, nil
+		}))
+		g.Add("broken", New(func(ctx context.Context) (int, error) {
+			time.Sleep(time.Second)
+			return 0, errors.New("failed")
+		}))
+		g.Add("dependent", New(func(ctx context.Context) (int, error) {
+			return 1, nil
+		}), "broken")
+
+		if r := g.Report(); r != nil {
+			t.Fatalf("got report %v before Prime, want nil", r)
+		}
+		start := time.Now()
+		g.Prime(context.Background())
+
+		r := g.Report()
+		if len(r) != 3 {
+			t.Fatalf("got %d timings, want 3", len(r))
+		}
+		if r[0].Name != "slow" || r[0].Duration != 2*time.Second || r[0].Attempts != 1 || r[0].Err != nil || !r[0].Start.Equal(start) {
+			t.Fatalf("got timing %+v for slow", r[0])
+		}
+		if r[2].Start != (time.Time{}) || r[2].Err == nil {
+			t.Fatalf("got timing %+v for a lazy whose dependency failed", r[2])
+		}
+
+		want := `NAME       DURATION  ATTEMPTS  OUTCOME
+slow       2s        1         ok
+broken     1s        1         failed
+dependent  0s        0         lazy: dependency "broken" failed
+`
+		if got := r.String(); got != want {
+			t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestGroup_PrimeErrors(t *testing.T) {
+	errDB := errors.New("db down")
+	errCache := errors.New("cache down")
+	var g Group
+	g.Add("db", New(func(ctx context.Context) (int, error) { return 0, errDB }))
+	g.Add("cache", New(func(ctx context.Context) (int, error) { return 0, errCache }))
+	g.Add("config", New(func(ctx context.Context) (int, error) { return 1, nil }))
+
+	err := g.Prime(context.Background())
+	if !errors.Is(err, errDB) || !errors.Is(err, errCache) {
+		t.Fatalf("got error %v, want both failures", err)
+	}
+	var merr *MemberError
+	if !errors.As(err, &merr) || merr.Name != "db" || merr.Err != errDB {
+		t.Fatalf("got first member error %#v, want db failing with %v", merr, errDB)
+	}
+
+	report := g.Report()
+	if got := report.Succeeded(); !slices.Equal(got, []string{"config"}) {
+		t.Fatalf("got succeeded %q, want [config]", got)
+	}
+	var failed []string
+	for _, t := range report.Failed() {
+		failed = append(failed, t.Name)
+	}
+	if !slices.Equal(failed, []string{"db", "cache"}) {
+		t.Fatalf("got failed %q, want [db cache]", failed)
+	}
+}
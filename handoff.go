@@ -0,0 +1,71 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// handoff tracks the callers interested in a detached initialization so that
+// it can be cancelled once all of them have stopped waiting.
+type handoff struct {
+	// merged reports whether cancellation causes wrap ErrAbandoned.
+	merged bool
+
+	mu      sync.Mutex
+	waiters int
+
+	// cancel cancels the run in progress after its leader has left. It is nil
+	// while the leader is still waiting or no run is in progress.
+	cancel context.CancelCauseFunc
+}
+
+// wait records that a caller started waiting for the semaphore.
+func (h *handoff) wait() {
+	h.mu.Lock()
+	h.waiters++
+	h.mu.Unlock()
+}
+
+// stopWaiting records that a caller stopped waiting for the semaphore. The
+// cause is nil if the caller acquired the semaphore; otherwise it is the cause
+// of the caller's context, and the run in progress is cancelled with it if its
+// leader and all other waiters have left.
+func (h *handoff) stopWaiting(cause error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.waiters--
+	if cause != nil && h.waiters == 0 && h.cancel != nil {
+		h.cancel(h.cause(cause))
+		h.cancel = nil
+	}
+}
+
+// leave records that the leader of the run in progress stopped waiting with
+// the given cause. The run is cancelled unless another caller is waiting to
+// take it over.
+func (h *handoff) leave(cancel context.CancelCauseFunc, cause error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.waiters == 0 {
+		cancel(h.cause(cause))
+		return
+	}
+	h.cancel = cancel
+}
+
+// finish records that the run in progress has finished.
+func (h *handoff) finish() {
+	h.mu.Lock()
+	h.cancel = nil
+	h.mu.Unlock()
+}
+
+// cause returns the cause with which to cancel a run after the last caller
+// left with the given cause.
+func (h *handoff) cause(last error) error {
+	if h.merged {
+		return fmt.Errorf("%w: %w", ErrAbandoned, last)
+	}
+	return last
+}
@@ -0,0 +1,157 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestWithLeaderHandoff_WaiterTakesOver(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			close(started)
+			select {
+			case <-proceed:
+				return 1, nil
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			}
+		}, WithLeaderHandoff())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() {
+			_, err := l.Get(ctx)
+			errs <- err
+		}()
+		<-started
+
+		results := make(chan int, 1)
+		go func() {
+			v, _ := l.Get(context.Background())
+			results <- v
+		}()
+		synctest.Wait()
+
+		// The leader leaves immediately; the waiter takes over.
+		cancel()
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Fatalf("got error %v, want %v", err, context.Canceled)
+		}
+
+		close(proceed)
+		if v := <-results; v != 1 {
+			t.Fatalf("got %d, want 1", v)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("function called %d times, want 1", got)
+		}
+	})
+}
+
+func TestWithLeaderHandoff_CancelledWhenAllLeave(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errLeader := errors.New("leader gone")
+		errWaiter := errors.New("waiter gone")
+		started := make(chan struct{})
+		causes := make(chan error, 1)
+
+		l := New(func(ctx context.Context) (int, error) {
+			close(started)
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+			return 0, ctx.Err()
+		}, WithLeaderHandoff())
+
+		leaderCtx, cancelLeader := context.WithCancelCause(context.Background())
+		go l.Get(leaderCtx)
+		<-started
+
+		waiterCtx, cancelWaiter := context.WithCancelCause(context.Background())
+		go l.Get(waiterCtx)
+		synctest.Wait()
+
+		// The run survives the leader leaving while a waiter remains.
+		cancelLeader(errLeader)
+		synctest.Wait()
+		select {
+		case cause := <-causes:
+			t.Fatalf("run cancelled with %v while a caller was waiting", cause)
+		default:
+		}
+
+		// It is cancelled once the last waiter leaves.
+		cancelWaiter(errWaiter)
+		if cause := <-causes; !errors.Is(cause, errWaiter) {
+			t.Fatalf("got cause %v, want %v", cause, errWaiter)
+		}
+	})
+}
+
+func TestWithLeaderHandoff_CancelledWithoutWaiters(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errLeader := errors.New("leader gone")
+		started := make(chan struct{})
+		causes := make(chan error, 1)
+
+		l := New(func(ctx context.Context) (int, error) {
+			close(started)
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+			return 0, ctx.Err()
+		}, WithLeaderHandoff())
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		go l.Get(ctx)
+		<-started
+
+		cancel(errLeader)
+		if cause := <-causes; !errors.Is(cause, errLeader) {
+			t.Fatalf("got cause %v, want %v", cause, errLeader)
+		}
+	})
+}
+
+func TestWithMergedContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan struct{})
+		causes := make(chan error, 1)
+
+		l := New(func(ctx context.Context) (int, error) {
+			close(started)
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+			return 0, ctx.Err()
+		}, WithMergedContext())
+
+		ctx1, cancel1 := context.WithCancel(context.Background())
+		go l.Get(ctx1)
+		<-started
+
+		ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+		defer cancel2()
+		go l.Get(ctx2)
+		synctest.Wait()
+
+		cancel1()
+		synctest.Wait()
+		select {
+		case cause := <-causes:
+			t.Fatalf("run cancelled with %v while a caller was waiting", cause)
+		default:
+		}
+
+		cause := <-causes
+		if !errors.Is(cause, ErrAbandoned) || !errors.Is(cause, context.DeadlineExceeded) {
+			t.Fatalf("got cause %v, want %v wrapping %v", cause, ErrAbandoned, context.DeadlineExceeded)
+		}
+	})
+}
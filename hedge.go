@@ -0,0 +1,74 @@
+package lazy
+
+import (
+	"context"
+	"time"
+)
+
+// hedge calls f and, each time delay elapses without a result, calls it again
+// in parallel, up to n calls in total. It returns the first successful result
+// and cancels the remaining calls. If every call fails, it returns the error of
+// the last call to fail. A panic in any call is re-raised in the calling
+// goroutine. If discard is not nil, it is called with the successful results
+// of the remaining calls once they finish.
+func hedge[T any](ctx context.Context, f func(context.Context) (T, error), delay time.Duration, n int, discard func(T)) (T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		value    T
+		err      error
+		panicked *any
+	}
+	results := make(chan result, n)
+	launch := func() {
+		go func() {
+			var r result
+			defer func() {
+				if p := recover(); p != nil {
+					r.panicked = &p
+				}
+				results <- r
+			}()
+			r.value, r.err = f(ctx)
+		}()
+	}
+
+	launch()
+	launched, running := 1, 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case r := <-results:
+			running--
+			if r.panicked != nil {
+				panic(*r.panicked)
+			}
+			if r.err == nil {
+				if discard != nil && running > 0 {
+					go func() {
+						for range running {
+							if r := <-results; r.err == nil && r.panicked == nil {
+								discard(r.value)
+							}
+						}
+					}()
+				}
+				return r.value, nil
+			}
+			if running == 0 {
+				var zero T
+				return zero, r.err
+			}
+		case <-timer.C:
+			if launched < n {
+				launch()
+				launched++
+				running++
+				timer.Reset(delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestWithHedging_FirstSuccessWins(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		var cancelled atomic.Bool
+
+		l := New(func(ctx context.Context) (int, error) {
+			n := calls.Add(1)
+			if n == 1 {
+				// The first call hangs until it is cancelled.
+				<-ctx.Done()
+				cancelled.Store(true)
+				return 0, ctx.Err()
+			}
+			select {
+			case <-time.After(time.Second):
+				return int(n), nil
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			}
+		}, WithHedging(100*time.Millisecond, 3))
+
+		start := time.Now()
+		result, err := l.Get(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != 2 {
+			t.Fatalf("got %d, want 2", result)
+		}
+		if elapsed := time.Since(start); elapsed != 1100*time.Millisecond {
+			t.Fatalf("took %v, want 1.1s", elapsed)
+		}
+
+		synctest.Wait()
+		if !cancelled.Load() {
+			t.Fatal("losing call was not cancelled")
+		}
+		if got := calls.Load(); got != 3 {
+			t.Fatalf("function called %d times, want 3", got)
+		}
+	})
+}
+
+func TestWithHedging_AllFail(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		errFail := errors.New("fail")
+
+		l := New(func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			time.Sleep(time.Second)
+			return 0, errFail
+		}, WithHedging(100*time.Millisecond, 2), WithMaxAttempts(1))
+
+		if _, err := l.Get(context.Background()); !errors.Is(err, errFail) || !errors.Is(err, ErrFrozen) {
+			t.Fatalf("got error %v, want %v wrapping %v", err, ErrFrozen, errFail)
+		}
+		if got := calls.Load(); got != 2 {
+			t.Fatalf("function called %d times, want 2", got)
+		}
+	})
+}
+
+func TestWithHedging_FastFailureIsNotHedged(t *testing.T) {
+	var calls atomic.Int32
+	errFail := errors.New("fail")
+
+	l := New(func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		return 0, errFail
+	}, WithHedging(time.Hour, 2))
+
+	if _, err := l.Get(context.Background()); !errors.Is(err, errFail) {
+		t.Fatalf("got error %v, want %v", err, errFail)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("function called %d times, want 1", got)
+	}
+}
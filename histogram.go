@@ -0,0 +1,90 @@
+package lazy
+
+import (
+	"iter"
+	"sync/atomic"
+	"time"
+)
+
+// histogramBounds are the upper bounds of the buckets of a Histogram.
+var histogramBounds = [...]time.Duration{
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// A Histogram is a distribution of durations, counted in buckets whose upper
+// bounds grow exponentially from a millisecond to a minute.
+type Histogram struct {
+	// Counts are the numbers of durations in each bucket, the last one
+	// counting those above a minute.
+	Counts [len(histogramBounds) + 1]uint64
+
+	// Count is the total number of durations.
+	Count uint64
+
+	// Sum is the sum of the durations.
+	Sum time.Duration
+}
+
+// Buckets returns an iterator over the upper bounds of the buckets of h, in
+// increasing order, and the cumulative number of durations at most each bound.
+// The durations above the last bound are only counted in Count.
+func (h Histogram) Buckets() iter.Seq2[time.Duration, uint64] {
+	return func(yield func(time.Duration, uint64) bool) {
+		var n uint64
+		for i, bound := range histogramBounds {
+			n += h.Counts[i]
+			if !yield(bound, n) {
+				return
+			}
+		}
+	}
+}
+
+// add adds the durations of o to h.
+func (h *Histogram) add(o Histogram) {
+	for i, n := range o.Counts {
+		h.Counts[i] += n
+	}
+	h.Count += o.Count
+	h.Sum += o.Sum
+}
+
+// histogramCounters record durations reported as a Histogram.
+type histogramCounters struct {
+	counts [len(histogramBounds) + 1]atomic.Uint64
+	sum    atomic.Int64
+}
+
+// observe records d.
+func (c *histogramCounters) observe(d time.Duration) {
+	i := 0
+	for i < len(histogramBounds) && d > histogramBounds[i] {
+		i++
+	}
+	c.counts[i].Add(1)
+	c.sum.Add(int64(d))
+}
+
+func (c *histogramCounters) histogram() Histogram {
+	var h Histogram
+	for i := range c.counts {
+		h.Counts[i] = c.counts[i].Load()
+		h.Count += h.Counts[i]
+	}
+	h.Sum = time.Duration(c.sum.Load())
+	return h
+}
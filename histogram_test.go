@@ -0,0 +1,76 @@
+package lazy
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	var c histogramCounters
+	for _, d := range []time.Duration{0, time.Millisecond, 3 * time.Millisecond, time.Hour} {
+		c.observe(d)
+	}
+	h := c.histogram()
+	if h.Count != 4 || h.Sum != time.Hour+4*time.Millisecond {
+		t.Fatalf("got count %d and sum %v, want 4 and %v", h.Count, h.Sum, time.Hour+4*time.Millisecond)
+	}
+	want := map[time.Duration]uint64{
+		time.Millisecond:        2,
+		2500 * time.Microsecond: 2,
+		5 * time.Millisecond:    3,
+		time.Minute:             3,
+	}
+	for bound, n := range h.Buckets() {
+		if w, ok := want[bound]; ok && n != w {
+			t.Errorf("got %d durations at most %v, want %d", n, bound, w)
+		}
+	}
+}
+
+func TestLazy_InfoLoadTimes(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		l := New(func(ctx context.Context) (int, error) {
+			time.Sleep(2 * time.Second)
+			return 1, nil
+		})
+		l.Prime(context.Background())
+
+		info := l.Info()
+		if info.LastLoad != 2*time.Second {
+			t.Errorf("got last load %v, want 2s", info.LastLoad)
+		}
+		if h := info.LoadTimes; h.Count != 1 || h.Sum != 2*time.Second {
+			t.Errorf("got %d loads taking %v, want 1 taking 2s", h.Count, h.Sum)
+		}
+	})
+}
+
+func TestLazy_InfoWaitTimes(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		proceed := make(chan struct{})
+		l := New(func(ctx context.Context) (int, error) {
+			<-proceed
+			return 1, nil
+		})
+		go l.Get(context.Background())
+		synctest.Wait()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			l.Get(context.Background())
+		}()
+		synctest.Wait()
+		if n := l.Info().Waiters; n != 1 {
+			t.Fatalf("got %d waiters, want 1", n)
+		}
+
+		time.Sleep(time.Second)
+		close(proceed)
+		<-done
+		if h := l.Info().WaitTimes; h.Count != 1 || h.Sum != time.Second {
+			t.Fatalf("got %d waits lasting %v, want 1 lasting 1s", h.Count, h.Sum)
+		}
+	})
+}
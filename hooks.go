@@ -0,0 +1,132 @@
+package lazy
+
+import (
+	"context"
+	"time"
+)
+
+// Hooks are callbacks invoked during the initializations of a Lazy, or of the
+// values of a Map, for logging, metrics and tracing. They are set with
+// WithHooks. Any of them may be nil. They must be safe for concurrent use and
+// should return quickly, since they run in the path of callers.
+type Hooks struct {
+	// OnStart is called before each call of the initialization function
+	// with the number of the attempt, counting from one and including the
+	// failed attempts before it. The returned context, if not nil, is
+	// passed to the function and to OnFinish instead of ctx, so that it can
+	// carry a span.
+	OnStart func(ctx context.Context, attempt int) context.Context
+
+	// OnFinish is called when a call of the initialization function
+	// returns, with its duration and error.
+	OnFinish func(ctx context.Context, d time.Duration, err error)
+
+	// OnWait is called when a caller starts waiting for the initialization
+	// of another caller, whose context, as returned by OnStart, is leader.
+	// The leader is nil if no initialization was observed in flight. The
+	// returned context, if not nil, is passed to OnWaitDone instead of ctx.
+	OnWait func(ctx, leader context.Context) context.Context
+
+	// OnWaitDone is called when the caller stops waiting, with the
+	// duration of the wait. If shared is true, the caller was served the
+	// outcome of the initialization it waited for, successful or failed
+	// with err; otherwise it stopped waiting with err, or calls the
+	// initialization function itself if err is nil.
+	OnWaitDone func(ctx context.Context, d time.Duration, shared bool, err error)
+}
+
+// start calls the OnStart hook of h, if any, and returns the context to pass
+// to the initialization function.
+func (h *Hooks) start(ctx context.Context, attempt int) context.Context {
+	if h == nil || h.OnStart == nil {
+		return ctx
+	}
+	if c := h.OnStart(ctx, attempt); c != nil {
+		return c
+	}
+	return ctx
+}
+
+// finish calls the OnFinish hook of h, if any.
+func (h *Hooks) finish(ctx context.Context, d time.Duration, err error) {
+	if h != nil && h.OnFinish != nil {
+		h.OnFinish(ctx, d, err)
+	}
+}
+
+// A waiter is a caller waiting for an initialization, as reported to Hooks.
+type waiter struct {
+	hooks *Hooks
+	ctx   context.Context
+	start time.Time
+}
+
+// wait calls the OnWait hook of h, if any, and returns the waiter to end with
+// done. It returns nil if h has neither an OnWait nor an OnWaitDone hook.
+func (h *Hooks) wait(ctx context.Context, a *attempt) *waiter {
+	if h == nil || (h.OnWait == nil && h.OnWaitDone == nil) {
+		return nil
+	}
+	var leader context.Context
+	if a != nil {
+		leader = a.ctx
+	}
+	return &waiter{hooks: h, ctx: h.startWait(ctx, leader), start: time.Now()}
+}
+
+// startWait calls the OnWait hook of h, if any, and returns the context to
+// pass to OnWaitDone.
+func (h *Hooks) startWait(ctx, leader context.Context) context.Context {
+	if h.OnWait == nil {
+		return ctx
+	}
+	if c := h.OnWait(ctx, leader); c != nil {
+		return c
+	}
+	return ctx
+}
+
+// done calls the OnWaitDone hook of w, if w is not nil.
+func (w *waiter) done(shared bool, err error) {
+	if w != nil && w.hooks.OnWaitDone != nil {
+		w.hooks.OnWaitDone(w.ctx, time.Since(w.start), shared, err)
+	}
+}
+
+// WithHooks sets hooks to be called during initializations, as described for
+// Hooks. It may be given more than once, for instance to combine logging and
+// tracing: the hooks are then called in order, each OnStart and OnWait hook
+// receiving the context returned by the previous one, and the other hooks
+// the context returned by the last one.
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		if o.hooks != nil {
+			hooks = joinHooks(*o.hooks, hooks)
+		}
+		o.hooks = &hooks
+	}
+}
+
+// joinHooks returns hooks that call the hooks of a and then those of b.
+func joinHooks(a, b Hooks) Hooks {
+	return Hooks{
+		OnStart: func(ctx context.Context, attempt int) context.Context {
+			return b.start(a.start(ctx, attempt), attempt)
+		},
+		OnFinish: func(ctx context.Context, d time.Duration, err error) {
+			a.finish(ctx, d, err)
+			b.finish(ctx, d, err)
+		},
+		OnWait: func(ctx, leader context.Context) context.Context {
+			return b.startWait(a.startWait(ctx, leader), leader)
+		},
+		OnWaitDone: func(ctx context.Context, d time.Duration, shared bool, err error) {
+			if a.OnWaitDone != nil {
+				a.OnWaitDone(ctx, d, shared, err)
+			}
+			if b.OnWaitDone != nil {
+				b.OnWaitDone(ctx, d, shared, err)
+			}
+		},
+	}
+}
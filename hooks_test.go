@@ -0,0 +1,97 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestWithHooks(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		type leaderKey struct{}
+		var (
+			mu     sync.Mutex
+			events []string
+		)
+		record := func(e string) {
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+		}
+		hooks := Hooks{
+			OnStart: func(ctx context.Context, attempt int) context.Context {
+				record(fmt.Sprint("start ", attempt))
+				return context.WithValue(ctx, leaderKey{}, true)
+			},
+			OnFinish: func(ctx context.Context, d time.Duration, err error) {
+				if ctx.Value(leaderKey{}) == nil {
+					t.Error("OnFinish not called with the context returned by OnStart")
+				}
+				record("finish " + d.String())
+			},
+			OnWait: func(ctx, leader context.Context) context.Context {
+				if leader == nil || leader.Value(leaderKey{}) == nil {
+					t.Error("OnWait not called with the context of the leader")
+				}
+				record("wait")
+				return ctx
+			},
+			OnWaitDone: func(ctx context.Context, d time.Duration, shared bool, err error) {
+				if !shared || err == nil {
+					t.Errorf("OnWaitDone called with (%v, %v), want the shared error", shared, err)
+				}
+				record("wait done " + d.String())
+			},
+		}
+		l := New(func(ctx context.Context) (int, error) {
+			if ctx.Value(leaderKey{}) == nil {
+				t.Error("f not called with the context returned by OnStart")
+			}
+			time.Sleep(time.Second)
+			return 0, errors.New("failed")
+		}, WithHooks(hooks), WithSharedResults())
+
+		var wg sync.WaitGroup
+		wg.Go(func() { l.Get(context.Background()) })
+		synctest.Wait()
+		wg.Go(func() { l.Get(context.Background()) })
+		wg.Wait()
+
+		want := []string{"start 1", "wait", "finish 1s", "wait done 1s"}
+		if !slices.Equal(events, want) {
+			t.Fatalf("got events %q, want %q", events, want)
+		}
+	})
+}
+
+func TestWithHooks_Combined(t *testing.T) {
+	type key struct{ name string }
+	var events []string
+	hooks := func(name string) Hooks {
+		return Hooks{
+			OnStart: func(ctx context.Context, attempt int) context.Context {
+				events = append(events, "start "+name)
+				return context.WithValue(ctx, key{name}, true)
+			},
+			OnFinish: func(ctx context.Context, d time.Duration, err error) {
+				if ctx.Value(key{"a"}) == nil || ctx.Value(key{"b"}) == nil {
+					t.Errorf("OnFinish of %s not called with the contexts of both hooks", name)
+				}
+				events = append(events, "finish "+name)
+			},
+		}
+	}
+	l := New(func(ctx context.Context) (int, error) { return 1, nil },
+		WithHooks(hooks("a")), WithHooks(hooks("b")))
+	l.Prime(context.Background())
+
+	want := []string{"start a", "start b", "finish a", "finish b"}
+	if !slices.Equal(events, want) {
+		t.Fatalf("got events %q, want %q", events, want)
+	}
+}
@@ -3,53 +3,557 @@ package lazy
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// Lazy is a value that is initialized on first use by calling a function. The
+// function executes at most once successfully; if it returns an error, future
+// calls will retry. A Lazy must be created with New and is safe for concurrent
+// use by multiple goroutines.
+type Lazy[T any] struct {
+	// f is the initialization function, replaced by SetFunc, and fgen counts
+	// its replacements. They are guarded by mu.
+	f     func(context.Context) (T, func(context.Context) error, error)
+	fgen  uint64
+	opts  options
+	sem   semaphore
+	value atomic.Pointer[T]
+
+	// panicked holds the recovered value of a panic in f when panics are
+	// propagated. It is guarded by sem.
+	panicked *any
+
+	// inflight is the most recent call of f.
+	inflight atomic.Pointer[attempt]
+
+	// retry is the retry policy state. It is guarded by sem.
+	retry retryState
+
+	handoff handoff
+
+	stats *lazyCounters
+
+	// failure is the error of the most recent call of f, or nil if it
+	// succeeded.
+	failure atomic.Pointer[error]
+
+	// loadTime is the duration of the most recent call of f.
+	loadTime atomic.Int64
+
+	// seq orders the initializations of lazies, for closing them in
+	// reverse order. It is zero if l has never been initialized.
+	seq atomic.Uint64
+
+	// generation counts the values cached by l.
+	generation atomic.Uint64
+
+	// mu serializes the changes of value with those of cleanup, the cleanup
+	// function of the cached value, if any, and of closed.
+	mu      sync.Mutex
+	cleanup func(context.Context) error
+	closed  atomic.Bool
+
+	// held mirrors value and cleanup for the runtime cleanup registered by
+	// WithCollectCleanup, which must not reference l. It is nil unless the
+	// option is set, and guarded by mu.
+	held *heldValue[T]
+
+	// watchers are the channels returned by Watch. They are guarded by mu.
+	watchers []chan T
+
+	// fallback is the value served by a Lazy returned by Fallback for the
+	// errors reported by fallbackMatch, and degraded reports whether Get
+	// has served it since a value was last cached.
+	fallback      *T
+	fallbackMatch func(error) bool
+	degraded      atomic.Bool
+
+	// onDone are the callbacks registered with OnDone. They are guarded by
+	// mu.
+	onDone []*doneCallback[T]
+}
+
+// An attempt records the outcome of a single call of f that is shared with
+// callers that waited for it.
+type attempt struct {
+	// err is set before done and is only read by callers that observed the
+	// attempt before it was done.
+	err  error
+	done atomic.Bool
+
+	// ctx is the context of the call, as returned by the OnStart hook. It
+	// is only set if l has hooks.
+	ctx context.Context
+}
+
+// New returns a Lazy whose value is initialized by calling f.
+func New[T any](f func(context.Context) (T, error), opts ...Option) *Lazy[T] {
+	return newLazy(noCleanup(f), newOptions(opts), new(lazyCounters))
+}
+
+func newLazy[T any](f func(context.Context) (T, func(context.Context) error, error), o options, stats *lazyCounters) *Lazy[T] {
+	l := &Lazy[T]{
+		f:     f,
+		opts:  o,
+		stats: stats,
+	}
+	l.sem.maxWaiters = l.opts.maxWaiters
+	l.handoff.merged = l.opts.mergedContext
+	if l.opts.collectCleanup {
+		l.held = new(heldValue[T])
+		runtime.AddCleanup(l, (*heldValue[T]).release, l.held)
+	}
+	return l
+}
+
 // Func wraps f so that it executes at most once successfully. Subsequent calls
 // return the cached result. If f returns an error, future calls will retry.
 // The returned function respects context cancellation while waiting to execute f.
-func Func[T any](f func(context.Context) (T, error)) func(context.Context) (T, error) {
-	// Use a struct so that there's a single heap allocation.
-	d := struct {
-		f     func(context.Context) (T, error)
-		done  atomic.Bool
-		sem   chan struct{}
-		value T
-	}{
-		f:   f,
-		sem: make(chan struct{}, 1),
+func Func[T any](f func(context.Context) (T, error), opts ...Option) func(context.Context) (T, error) {
+	return New(f, opts...).Get
+}
+
+// Value is like Func but returns a function that takes no arguments, mirroring
+// the ergonomics of sync.OnceValues. The initialization function is called with
+// context.Background, so waiting callers cannot be cancelled.
+func Value[T any](f func(context.Context) (T, error), opts ...Option) func() (T, error) {
+	return New(f, opts...).Bind(context.Background())
+}
+
+// Get returns the value of l, calling the initialization function if no value
+// has been cached yet. Get respects context cancellation while waiting for
+// another goroutine's initialization to finish, returning context.Cause(ctx).
+//
+// Waiting callers are served in FIFO order: if an initialization fails, the
+// caller that has waited longest is the next to retry it, so no caller is
+// starved by callers that arrived later.
+//
+// If the initialization function calls Get on the same Lazy with its context,
+// or one derived from it, Get returns an error wrapping ErrReentrant instead
+// of deadlocking.
+func (l *Lazy[T]) Get(ctx context.Context) (T, error) {
+	if p := l.value.Load(); p != nil {
+		return *p, nil
+	}
+	v, err := l.getSlow(ctx)
+	if err != nil && l.fallback != nil {
+		return l.degrade(ctx, err)
+	}
+	return v, err
+}
+
+// getSlow is the slow path of Get, when no value is cached.
+func (l *Lazy[T]) getSlow(ctx context.Context) (T, error) {
+	if l.closed.Load() {
+		var zero T
+		return zero, ErrClosed
+	}
+	if err := l.checkReentry(ctx); err != nil {
+		var zero T
+		return zero, err
 	}
 
-	return func(ctx context.Context) (T, error) {
-		if d.done.Load() {
-			return d.value, nil
+	// Remember the attempt in flight, if any, so that its outcome can be shared
+	// once the semaphore is acquired.
+	a := l.inflight.Load()
+	if a != nil && a.done.Load() {
+		a = nil
+	}
+
+	w, err := l.acquire(ctx, a)
+	if err != nil {
+		if p := l.value.Load(); p != nil && l.opts.checkOnCancel {
+			w.done(true, nil)
+			return *p, nil
 		}
+		w.done(false, err)
+		var zero T
+		return zero, err
+	}
+	return l.run(ctx, a, w)
+}
 
-		select {
-		case d.sem <- struct{}{}:
-			defer func() { <-d.sem }()
-		case <-ctx.Done():
+// A Result holds the outcome of an asynchronous Get.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// GetChan is like Get but returns immediately with a channel that receives
+// the result once it is available, so that callers can select on it alongside
+// other events. The channel is buffered and receives exactly one Result; it is
+// not closed. The ctx governs the underlying Get as usual.
+func (l *Lazy[T]) GetChan(ctx context.Context) <-chan Result[T] {
+	ch := make(chan Result[T], 1)
+	if p := l.value.Load(); p != nil {
+		ch <- Result[T]{Value: *p}
+		return ch
+	}
+	go func() {
+		v, err := l.Get(ctx)
+		ch <- Result[T]{Value: v, Err: err}
+	}()
+	return ch
+}
+
+// TryGet is like Get but never blocks waiting for another goroutine. It returns
+// the cached value if there is one, ErrBusy if another goroutine is
+// initializing the value, and otherwise initializes the value itself.
+func (l *Lazy[T]) TryGet(ctx context.Context) (T, error) {
+	if p := l.value.Load(); p != nil {
+		return *p, nil
+	}
+	if l.closed.Load() {
+		var zero T
+		return zero, ErrClosed
+	}
+	if err := l.checkReentry(ctx); err != nil {
+		var zero T
+		return zero, err
+	}
+	if !l.sem.tryAcquire() {
+		var zero T
+		return zero, ErrBusy
+	}
+	return l.run(ctx, nil, nil)
+}
+
+// run initializes the value unless it has been cached or the outcome of the
+// in-flight attempt a, observed before acquiring sem, should be shared. It must
+// be called with sem held and releases it. The waiter w, if not nil, is done
+// once the caller is either served a shared outcome or about to initialize the
+// value.
+func (l *Lazy[T]) run(ctx context.Context, a *attempt, w *waiter) (T, error) {
+	// Check again after acquiring the semaphore.
+	if p := l.value.Load(); p != nil {
+		l.sem.release()
+		l.stats.deduplicated.Add(1)
+		w.done(true, nil)
+		return *p, nil
+	}
+	l.resetRetry()
+	if l.panicked != nil {
+		l.sem.release()
+		w.done(true, &PanicError{Value: *l.panicked})
+		panic(*l.panicked)
+	}
+	if a != nil && a.err != nil {
+		l.sem.release()
+		l.stats.deduplicated.Add(1)
+		w.done(true, a.err)
+		var zero T
+		return zero, a.err
+	}
+	w.done(false, nil)
+
+	if l.opts.detached || l.opts.handoff {
+		return l.executeDetached(ctx)
+	}
+	defer l.sem.release()
+	return l.execute(ctx)
+}
+
+// acquire acquires sem, returning the cause of ctx if it is done first. If it
+// has to wait for the in-flight attempt a, it returns the waiter reported to
+// the hooks of l, if any.
+func (l *Lazy[T]) acquire(ctx context.Context, a *attempt) (*waiter, error) {
+	if l.sem.tryAcquire() {
+		return nil, nil
+	}
+	w := l.opts.hooks.wait(ctx, a)
+	defer l.traceWait(ctx)()
+	start := time.Now()
+	defer func() { l.stats.waitTimes.observe(time.Since(start)) }()
+
+	if d := l.opts.maxWait; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, d, ErrWaitTimeout)
+		defer cancel()
+	}
+	if l.opts.handoff {
+		l.handoff.wait()
+	}
+	err := l.sem.acquire(ctx)
+	if l.opts.handoff {
+		l.handoff.stopWaiting(err)
+	}
+	return w, err
+}
+
+// execute calls f if the retry policy allows it and caches a successful
+// result. It must be called with sem held.
+func (l *Lazy[T]) execute(ctx context.Context) (T, error) {
+	if l.closed.Load() {
+		var zero T
+		return zero, ErrClosed
+	}
+	if err := l.checkRetry(); err != nil {
+		var zero T
+		return zero, l.wrap(err)
+	}
+	if d := l.opts.minDeadline; d > 0 {
+		// In detached mode ctx has no deadline, so the guard never applies.
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
 			var zero T
-			return zero, ctx.Err()
+			return zero, l.wrap(context.DeadlineExceeded)
 		}
+	}
 
-		// Check again after acquiring the semaphore.
-		if d.done.Load() {
-			return d.value, nil
+	n := l.retry.failures + 1
+	ctx, endTask := l.traceTask(ctx, n)
+	defer endTask()
+	ctx = l.opts.hooks.start(ctx, n)
+	a := new(attempt)
+	if l.opts.hooks != nil {
+		a.ctx = ctx
+	}
+	l.inflight.Store(a)
+	defer a.done.Store(true)
+
+	l.mu.Lock()
+	f, fgen := l.f, l.fgen
+	l.mu.Unlock()
+
+	l.stats.loads.Add(1)
+	start := time.Now()
+	stopWatching := l.watchSlow()
+	value, cleanup, err := l.labeledCall(ctx, n, f)
+	stopWatching()
+	d := time.Since(start)
+	l.loadTime.Store(int64(d))
+	l.stats.loadTimes.observe(d)
+	l.opts.hooks.finish(ctx, d, err)
+	if err != nil {
+		l.stats.loadErrors.Add(1)
+		l.failure.Store(&err)
+		shared := l.opts.sharedResults && ctx.Err() == nil
+		err = l.wrap(l.recordFailure(ctx, err))
+		var perr *PanicError
+		if errors.As(err, &perr) || shared {
+			a.err = err
 		}
+		var zero T
+		l.fireDone(zero, err, 0)
+		return zero, err
+	}
 
-		value, err := d.f(ctx)
-		if err != nil {
+	// A concurrent Set takes precedence over the result of f.
+	l.failure.Store(nil)
+	l.mu.Lock()
+	if l.closed.Load() {
+		l.mu.Unlock()
+		releaseValue(value, cleanup)
+		var zero T
+		return zero, ErrClosed
+	}
+	if l.fgen != fgen {
+		// SetFunc replaced f during the call: serve its result uncached,
+		// unless it must be released, in which case call the new f.
+		l.mu.Unlock()
+		if cleanup == nil {
+			return value, nil
+		}
+		runCleanup(cleanup)
+		l.resetRetry()
+		return l.execute(ctx)
+	}
+	if !l.value.CompareAndSwap(nil, &value) {
+		l.mu.Unlock()
+		runCleanup(cleanup)
+		return *l.value.Load(), nil
+	}
+	l.holdLocked(&value, cleanup)
+	l.cachedLocked(&value)
+	gen := l.generation.Load()
+	l.mu.Unlock()
+	l.initialized()
+	l.fireDone(value, nil, gen)
+	return value, nil
+}
+
+// executeDetached runs execute in a new goroutine under a context that is not
+// cancelled with ctx, and waits for it to finish or for ctx to be done. The
+// goroutine releases sem when it finishes. A panic is re-raised in the calling
+// goroutine, or in the new goroutine if the caller has stopped waiting. With
+// leader handoff, the run is cancelled once the caller and all waiting callers
+// have stopped waiting. It must be called with sem held.
+func (l *Lazy[T]) executeDetached(ctx context.Context) (T, error) {
+	type outcome struct {
+		value    T
+		err      error
+		panicked *any
+	}
+
+	runCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+
+	var (
+		mu   sync.Mutex
+		left bool
+		ch   = make(chan outcome, 1)
+	)
+	go func() {
+		defer l.sem.release()
+		defer cancel(nil)
+		if l.opts.handoff {
+			defer l.handoff.finish()
+		}
+
+		var o outcome
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					o.panicked = &r
+				}
+			}()
+			o.value, o.err = l.execute(runCtx)
+		}()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if left && o.panicked != nil {
+			panic(*o.panicked)
+		}
+		ch <- o
+	}()
+
+	var o outcome
+	select {
+	case o = <-ch:
+	case <-ctx.Done():
+		mu.Lock()
+		select {
+		case o = <-ch:
+			mu.Unlock()
+		default:
+			left = true
+			if l.opts.handoff {
+				l.handoff.leave(cancel, context.Cause(ctx))
+			}
+			mu.Unlock()
 			var zero T
-			return zero, err
+			return zero, context.Cause(ctx)
+		}
+	}
+	if o.panicked != nil {
+		panic(*o.panicked)
+	}
+	return o.value, o.err
+}
+
+// wrap wraps err in an Error if l has a name or call site.
+func (l *Lazy[T]) wrap(err error) error {
+	if l.opts.name == "" && l.opts.site == "" {
+		return err
+	}
+	return &Error{Name: l.opts.name, Site: l.opts.site, Err: err}
+}
+
+// call invokes f, the initialization function of l. It must be called with
+// sem held.
+func (l *Lazy[T]) call(ctx context.Context, f func(context.Context) (T, func(context.Context) error, error)) (value T, cleanup func(context.Context) error, err error) {
+	ctx = l.markReentry(ctx)
+
+	if l.opts.recoverPanics || l.opts.propagatePanics {
+		defer func() {
+			r := recover()
+			switch {
+			case r == nil:
+			case l.opts.recoverPanics:
+				err = &PanicError{Value: r, Stack: debug.Stack()}
+			default:
+				l.panicked = &r
+				panic(r)
+			}
+		}()
+	}
+	if d := l.opts.attemptTimeout; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	if n := l.opts.hedgeAttempts; n > 1 {
+		f := func(ctx context.Context) (withCleanup[T], error) {
+			v, cleanup, err := f(ctx)
+			return withCleanup[T]{v, cleanup}, err
 		}
+		r, err := hedge(ctx, f, l.opts.hedgeDelay, n, func(r withCleanup[T]) {
+			runCleanup(r.cleanup)
+		})
+		return r.value, r.cleanup, err
+	}
+	return f(ctx)
+}
 
-		d.value = value
-		d.done.Store(true)
+// peek returns the cached value of l, if any.
+func (l *Lazy[T]) peek() (T, bool) {
+	if p := l.value.Load(); p != nil {
+		return *p, true
+	}
+	var zero T
+	return zero, false
+}
 
-		d.f = nil // Allow f to be garbage collected.
+// unused reports whether l holds no state worth keeping: no value, no
+// initialization in progress or waited for, no sticky panic, no pending retry
+// delay and no failures that the retry policy depends on.
+func (l *Lazy[T]) unused() bool {
+	if l.value.Load() != nil || !l.sem.tryAcquire() {
+		return false
+	}
+	defer l.sem.release()
+	if l.panicked != nil || time.Now().Before(l.retry.notBefore) {
+		return false
+	}
+	return l.retry.failures == 0 || !l.opts.hasRetryPolicy()
+}
+
+// busy reports whether an initialization of l is in progress.
+func (l *Lazy[T]) busy() bool {
+	return l.sem.busy()
+}
+
+// Bind returns a function that calls Get with ctx. It is useful for call sites
+// that have no context of their own and should share a base context instead.
+func (l *Lazy[T]) Bind(ctx context.Context) func() (T, error) {
+	return func() (T, error) {
+		return l.Get(ctx)
+	}
+}
+
+// MustGet is like Get but panics if the value cannot be initialized. It is
+// intended for program-level singletons whose initialization failure is fatal.
+func (l *Lazy[T]) MustGet(ctx context.Context) T {
+	return Must(l.Get)(ctx)
+}
+
+// Must wraps f, as returned by Func, so that it panics instead of returning an
+// error.
+func Must[T any](f func(context.Context) (T, error)) func(context.Context) T {
+	return func(ctx context.Context) T {
+		v, err := f(ctx)
+		if err != nil {
+			panic(fmt.Errorf("lazy: initialization of %v failed: %w", reflect.TypeFor[T](), err))
+		}
+		return v
+	}
+}
 
-		return d.value, nil
+// Set caches v as the value of l without calling the initialization function,
+// replacing any previously cached value. Callers waiting for an initialization
+// that is in flight when Set is called receive v; the in-flight result is
+// discarded. Set has no effect once l is closed.
+func (l *Lazy[T]) Set(v T) {
+	old, gen := l.swap(&v)
+	l.initialized()
+	runCleanup(old)
+	if gen != 0 {
+		l.fireDone(v, nil, gen)
 	}
 }
@@ -312,3 +312,246 @@ func TestFunc_SemaphoreReleasedAfterError(t *testing.T) {
 		}
 	})
 }
+
+func TestLazy_SetSkipsInitialization(t *testing.T) {
+	var calls atomic.Int32
+
+	l := New(func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		return 1, nil
+	})
+	l.Set(42)
+
+	result, err := l.Get(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 42 {
+		t.Fatalf("got %d, want 42", result)
+	}
+	if got := calls.Load(); got != 0 {
+		t.Fatalf("function called %d times, want 0", got)
+	}
+}
+
+func TestLazy_SetReplacesCachedValue(t *testing.T) {
+	l := New(func(ctx context.Context) (int, error) {
+		return 1, nil
+	})
+
+	if result, _ := l.Get(context.Background()); result != 1 {
+		t.Fatalf("got %d, want 1", result)
+	}
+
+	l.Set(2)
+
+	if result, _ := l.Get(context.Background()); result != 2 {
+		t.Fatalf("got %d, want 2", result)
+	}
+}
+
+func TestLazy_SetDuringInitialization(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			close(started)
+			<-proceed
+			return 1, nil
+		})
+
+		results := make(chan int, 2)
+		go func() {
+			v, _ := l.Get(context.Background())
+			results <- v
+		}()
+		<-started
+
+		// Second caller blocks on the semaphore.
+		go func() {
+			v, _ := l.Get(context.Background())
+			results <- v
+		}()
+		synctest.Wait()
+
+		l.Set(2)
+		close(proceed)
+
+		// Both callers see the value passed to Set.
+		for range 2 {
+			if v := <-results; v != 2 {
+				t.Errorf("got %d, want 2", v)
+			}
+		}
+	})
+}
+
+func TestLazy_MustGetPanicsOnError(t *testing.T) {
+	errFatal := errors.New("fatal")
+
+	l := New(func(ctx context.Context) (int, error) {
+		return 0, errFatal
+	})
+
+	defer func() {
+		err, ok := recover().(error)
+		if !ok {
+			t.Fatal("expected panic with error value")
+		}
+		if !errors.Is(err, errFatal) {
+			t.Fatalf("got panic %v, want wrapped %v", err, errFatal)
+		}
+	}()
+	l.MustGet(context.Background())
+}
+
+func TestMust(t *testing.T) {
+	get := Must(Func(func(ctx context.Context) (string, error) {
+		return "ok", nil
+	}))
+
+	if got := get(context.Background()); got != "ok" {
+		t.Fatalf("got %q, want %q", got, "ok")
+	}
+}
+
+func TestValue(t *testing.T) {
+	var calls atomic.Int32
+
+	get := Value(func(ctx context.Context) (int, error) {
+		if ctx == nil {
+			return 0, errors.New("nil context")
+		}
+		calls.Add(1)
+		return 7, nil
+	})
+
+	for range 2 {
+		result, err := get()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != 7 {
+			t.Fatalf("got %d, want 7", result)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("function called %d times, want 1", got)
+	}
+}
+
+func TestLazy_BindUsesBaseContext(t *testing.T) {
+	type ctxKey struct{}
+
+	l := New(func(ctx context.Context) (string, error) {
+		v, _ := ctx.Value(ctxKey{}).(string)
+		return v, nil
+	})
+	get := l.Bind(context.WithValue(context.Background(), ctxKey{}, "base"))
+
+	result, err := get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "base" {
+		t.Fatalf("got %q, want %q", result, "base")
+	}
+}
+
+func TestFunc_ContextCancellationCause(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errShutdown := errors.New("shutting down")
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+
+		f := Func(func(ctx context.Context) (int, error) {
+			close(started)
+			<-proceed
+			return 1, nil
+		})
+
+		go f(context.Background())
+		<-started
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		errs := make(chan error, 1)
+		go func() {
+			_, err := f(ctx)
+			errs <- err
+		}()
+		synctest.Wait()
+
+		cancel(errShutdown)
+		if err := <-errs; !errors.Is(err, errShutdown) {
+			t.Fatalf("got error %v, want %v", err, errShutdown)
+		}
+
+		close(proceed)
+	})
+}
+
+func TestLazy_TryGet(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			close(started)
+			<-proceed
+			return 1, nil
+		})
+
+		done := make(chan struct{})
+		go func() {
+			l.TryGet(context.Background())
+			close(done)
+		}()
+		<-started
+
+		// Another goroutine is initializing.
+		if _, err := l.TryGet(context.Background()); !errors.Is(err, ErrBusy) {
+			t.Fatalf("got error %v, want %v", err, ErrBusy)
+		}
+
+		close(proceed)
+		<-done
+
+		result, err := l.TryGet(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != 1 {
+			t.Fatalf("got %d, want 1", result)
+		}
+	})
+}
+
+func TestLazy_GetChan(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		proceed := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			<-proceed
+			return 1, nil
+		})
+
+		ch := l.GetChan(context.Background())
+		synctest.Wait()
+		select {
+		case r := <-ch:
+			t.Fatalf("received %v before initialization finished", r)
+		default:
+		}
+
+		close(proceed)
+		if r := <-ch; r.Err != nil || r.Value != 1 {
+			t.Fatalf("got %+v, want value 1", r)
+		}
+
+		// Once cached, the result is available immediately.
+		if r := <-l.GetChan(context.Background()); r.Err != nil || r.Value != 1 {
+			t.Fatalf("got %+v, want value 1", r)
+		}
+	})
+}
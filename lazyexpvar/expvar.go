@@ -0,0 +1,49 @@
+// Package lazyexpvar publishes the counters of the lazies and maps registered
+// with package lazy as an expvar variable.
+//
+// The counters are those of lazy.Stats and lazy.MapStats. A Lazy does not count
+// the calls served its cached value, so that they stay free of shared writes;
+// hits and misses are therefore only published for maps, and no total of calls
+// is published for either. Failures are published as load errors.
+package lazyexpvar
+
+import (
+	"expvar"
+
+	"github.com/acycl/lazy"
+)
+
+// Publish publishes the counters of the registered lazies and maps as the
+// expvar variable called name, so that they are served with the other variables
+// of package expvar. The variable is a JSON object with a member for each
+// registered name, holding its loads, load errors, deduplicated callers and the
+// duration of its last load in nanoseconds and, for maps only, their hits and
+// misses. It is computed each time it is read. Like expvar.Publish, Publish
+// panics if name is already published.
+func Publish(name string) {
+	expvar.Publish(name, expvar.Func(value))
+}
+
+// value returns the value of the variable published by Publish.
+func value() any {
+	vars := make(map[string]map[string]any)
+	for name, h := range lazy.Registered() {
+		in, ok := h.(interface{ Info() lazy.Info })
+		if !ok {
+			continue
+		}
+		info := in.Info()
+		v := map[string]any{
+			"loads":        info.Stats.Loads,
+			"load_errors":  info.Stats.LoadErrors,
+			"deduplicated": info.Stats.Deduplicated,
+			"last_load_ns": info.LastLoad.Nanoseconds(),
+		}
+		if info.Size >= 0 {
+			v["hits"] = info.Stats.Hits
+			v["misses"] = info.Stats.Misses
+		}
+		vars[name] = v
+	}
+	return vars
+}
@@ -0,0 +1,41 @@
+package lazyexpvar
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/acycl/lazy"
+)
+
+func TestPublish(t *testing.T) {
+	l := lazy.New(func(ctx context.Context) (int, error) { return 1, nil })
+	m := lazy.NewMap(func(ctx context.Context, key string) (string, error) { return key, nil })
+	lazy.Register("test.expvar.lazy", l)
+	lazy.Register("test.expvar.map", m)
+	defer lazy.Unregister("test.expvar.lazy")
+	defer lazy.Unregister("test.expvar.map")
+
+	l.Prime(context.Background())
+	m.Get(context.Background(), "a")
+	m.Get(context.Background(), "a")
+
+	if expvar.Get("test.lazy") == nil { // when run with -count
+		Publish("test.lazy")
+	}
+	var vars map[string]map[string]int64
+	if err := json.Unmarshal([]byte(expvar.Get("test.lazy").String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if got := vars["test.expvar.lazy"]["loads"]; got != 1 {
+		t.Errorf("got %d lazy loads, want 1", got)
+	}
+	if _, ok := vars["test.expvar.lazy"]["hits"]; ok {
+		t.Error("got hits for a lazy")
+	}
+	mv := vars["test.expvar.map"]
+	if mv["loads"] != 1 || mv["hits"] != 1 || mv["misses"] != 1 {
+		t.Errorf("got map counters %v, want 1 load, 1 hit and 1 miss", mv)
+	}
+}
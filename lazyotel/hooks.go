@@ -0,0 +1,62 @@
+// Package lazyotel traces the initializations of lazies and maps with
+// OpenTelemetry. It is a separate module so that package lazy does not depend
+// on OpenTelemetry.
+package lazyotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/acycl/lazy"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Hooks returns hooks, to be set with lazy.WithHooks, that trace the
+// initializations of the lazy or map called name with tracer. Each call of the
+// initialization function runs in a span named "lazy.init " followed by name,
+// with the attempt number as an attribute. Each caller that waits for another
+// caller's initialization gets a span named "lazy.wait " followed by name,
+// linked to the span of that initialization and recording whether the caller
+// was served its outcome as the lazy.cache_hit attribute.
+func Hooks(tracer trace.Tracer, name string) lazy.Hooks {
+	nameAttr := attribute.String("lazy.name", name)
+	return lazy.Hooks{
+		OnStart: func(ctx context.Context, attempt int) context.Context {
+			ctx, _ = tracer.Start(ctx, "lazy.init "+name, trace.WithAttributes(
+				nameAttr,
+				attribute.Int("lazy.attempt", attempt),
+				attribute.Bool("lazy.cache_hit", false),
+			))
+			return ctx
+		},
+		OnFinish: func(ctx context.Context, d time.Duration, err error) {
+			end(trace.SpanFromContext(ctx), err)
+		},
+		OnWait: func(ctx, leader context.Context) context.Context {
+			opts := []trace.SpanStartOption{trace.WithAttributes(nameAttr)}
+			if leader != nil {
+				if sc := trace.SpanContextFromContext(leader); sc.IsValid() {
+					opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+				}
+			}
+			ctx, _ = tracer.Start(ctx, "lazy.wait "+name, opts...)
+			return ctx
+		},
+		OnWaitDone: func(ctx context.Context, d time.Duration, shared bool, err error) {
+			span := trace.SpanFromContext(ctx)
+			span.SetAttributes(attribute.Bool("lazy.cache_hit", shared))
+			end(span, err)
+		},
+	}
+}
+
+// end records err, if any, in span and ends it.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
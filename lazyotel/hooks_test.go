@@ -0,0 +1,71 @@
+package lazyotel
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+
+	"github.com/acycl/lazy"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHooks(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		rec := tracetest.NewSpanRecorder()
+		tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+
+		proceed := make(chan struct{})
+		l := lazy.New(func(ctx context.Context) (int, error) {
+			<-proceed
+			return 1, nil
+		}, lazy.WithHooks(Hooks(tracer, "db")))
+
+		go l.Get(context.Background())
+		synctest.Wait()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			l.Get(context.Background())
+		}()
+		synctest.Wait()
+		close(proceed)
+		<-done
+
+		spans := rec.Ended()
+		if len(spans) != 2 {
+			t.Fatalf("got %d spans, want 2", len(spans))
+		}
+		var init, wait sdktrace.ReadOnlySpan
+		for _, s := range spans {
+			switch s.Name() {
+			case "lazy.init db":
+				init = s
+			case "lazy.wait db":
+				wait = s
+			}
+		}
+		if init == nil || wait == nil {
+			t.Fatalf("got spans %v, want an init and a wait span", spans)
+		}
+		if !hasAttr(init, attribute.Int("lazy.attempt", 1)) {
+			t.Errorf("init span attributes %v lack the attempt", init.Attributes())
+		}
+		if !hasAttr(wait, attribute.Bool("lazy.cache_hit", true)) {
+			t.Errorf("wait span attributes %v lack the cache hit", wait.Attributes())
+		}
+		if links := wait.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != init.SpanContext().SpanID() {
+			t.Errorf("wait span links %v, want a link to the init span", links)
+		}
+	})
+}
+
+func hasAttr(s sdktrace.ReadOnlySpan, kv attribute.KeyValue) bool {
+	for _, a := range s.Attributes() {
+		if a == kv {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+// Package lazyprom exports the state of the lazies and maps registered with
+// package lazy as Prometheus metrics. It is a separate module so that package
+// lazy does not depend on the Prometheus client.
+package lazyprom
+
+import (
+	"github.com/acycl/lazy"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	loadSeconds = prometheus.NewDesc(
+		"lazy_load_duration_seconds",
+		"Duration of the loads of a lazy or map.",
+		[]string{"name"}, nil)
+	waitSeconds = prometheus.NewDesc(
+		"lazy_wait_duration_seconds",
+		"Duration of the waits of callers for the loads of other callers.",
+		[]string{"name"}, nil)
+	loads = prometheus.NewDesc(
+		"lazy_loads_total",
+		"Number of loads of a lazy or map.",
+		[]string{"name"}, nil)
+	loadErrors = prometheus.NewDesc(
+		"lazy_load_errors_total",
+		"Number of failed loads of a lazy or map.",
+		[]string{"name"}, nil)
+	hits = prometheus.NewDesc(
+		"lazy_hits_total",
+		"Number of reads of a map served a cached value.",
+		[]string{"name"}, nil)
+	misses = prometheus.NewDesc(
+		"lazy_misses_total",
+		"Number of reads of a map that waited for a load.",
+		[]string{"name"}, nil)
+	waiters = prometheus.NewDesc(
+		"lazy_waiters",
+		"Number of callers waiting for a load of a lazy or map.",
+		[]string{"name"}, nil)
+	size = prometheus.NewDesc(
+		"lazy_cached_values",
+		"Number of values cached by a map.",
+		[]string{"name"}, nil)
+)
+
+// A Collector is a prometheus.Collector over the lazies and maps registered
+// with lazy.Register, labeling their metrics with their registered name. It
+// reads their state each time it is collected.
+type Collector struct{}
+
+// NewCollector returns a Collector, to be registered with a
+// prometheus.Registerer.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{loadSeconds, waitSeconds, loads, loadErrors, hits, misses, waiters, size} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for name, h := range lazy.Registered() {
+		in, ok := h.(interface{ Info() lazy.Info })
+		if !ok {
+			continue
+		}
+		info := in.Info()
+
+		ch <- histogram(loadSeconds, info.LoadTimes, name)
+		ch <- histogram(waitSeconds, info.WaitTimes, name)
+		ch <- prometheus.MustNewConstMetric(loads, prometheus.CounterValue, float64(info.Stats.Loads), name)
+		ch <- prometheus.MustNewConstMetric(loadErrors, prometheus.CounterValue, float64(info.Stats.LoadErrors), name)
+		ch <- prometheus.MustNewConstMetric(waiters, prometheus.GaugeValue, float64(info.Waiters), name)
+		if info.Size >= 0 {
+			ch <- prometheus.MustNewConstMetric(hits, prometheus.CounterValue, float64(info.Stats.Hits), name)
+			ch <- prometheus.MustNewConstMetric(misses, prometheus.CounterValue, float64(info.Stats.Misses), name)
+			ch <- prometheus.MustNewConstMetric(size, prometheus.GaugeValue, float64(info.Size), name)
+		}
+	}
+}
+
+// histogram returns h as a metric described by desc.
+func histogram(desc *prometheus.Desc, h lazy.Histogram, name string) prometheus.Metric {
+	buckets := make(map[float64]uint64)
+	for bound, n := range h.Buckets() {
+		buckets[bound.Seconds()] = n
+	}
+	return prometheus.MustNewConstHistogram(desc, h.Count, h.Sum.Seconds(), buckets, name)
+}
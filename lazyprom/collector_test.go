@@ -0,0 +1,50 @@
+package lazyprom
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acycl/lazy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	l := lazy.New(func(ctx context.Context) (int, error) { return 1, nil })
+	m := lazy.NewMap(func(ctx context.Context, key string) (string, error) { return key, nil })
+	lazy.Register("test.lazy", l)
+	lazy.Register("test.map", m)
+	defer lazy.Unregister("test.lazy")
+	defer lazy.Unregister("test.map")
+
+	l.Prime(context.Background())
+	m.Get(context.Background(), "a")
+	m.Get(context.Background(), "a")
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(NewCollector())
+	want := `
+# HELP lazy_hits_total Number of reads of a map served a cached value.
+# TYPE lazy_hits_total counter
+lazy_hits_total{name="test.map"} 1
+# HELP lazy_loads_total Number of loads of a lazy or map.
+# TYPE lazy_loads_total counter
+lazy_loads_total{name="test.lazy"} 1
+lazy_loads_total{name="test.map"} 1
+# HELP lazy_misses_total Number of reads of a map that waited for a load.
+# TYPE lazy_misses_total counter
+lazy_misses_total{name="test.map"} 1
+# HELP lazy_cached_values Number of values cached by a map.
+# TYPE lazy_cached_values gauge
+lazy_cached_values{name="test.map"} 1
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(want),
+		"lazy_hits_total", "lazy_loads_total", "lazy_misses_total", "lazy_cached_values")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := testutil.GatherAndCount(reg, "lazy_load_duration_seconds"); err != nil || n != 2 {
+		t.Fatalf("got %d load duration histograms (error %v), want 2", n, err)
+	}
+}
@@ -0,0 +1,87 @@
+// Package lazyslog logs the initializations of lazies and maps with log/slog.
+package lazyslog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/acycl/lazy"
+)
+
+// Options configure the hooks returned by Hooks. The zero value logs with the
+// default levels.
+type Options struct {
+	// StartLevel is the level at which the start of an initialization is
+	// logged. The default is slog.LevelDebug.
+	StartLevel slog.Leveler
+
+	// FinishLevel is the level at which a successful initialization is
+	// logged. The default is slog.LevelInfo.
+	FinishLevel slog.Leveler
+
+	// FailLevel is the level at which a failed initialization is logged.
+	// The default is slog.LevelError.
+	FailLevel slog.Leveler
+
+	// WaitLevel is the level at which long waits for the initialization of
+	// another caller are logged. The default is slog.LevelWarn.
+	WaitLevel slog.Leveler
+
+	// LongWait is the duration from which waits are logged. The default is
+	// one second.
+	LongWait time.Duration
+}
+
+type attemptKey struct{}
+
+// Hooks returns hooks, to be set with lazy.WithHooks, that log the
+// initializations of the lazy or map called name to logger: their start with
+// the attempt number, their end with their duration, their failure with the
+// error and attempt number, and the waits for them that last at least
+// opts.LongWait. Each record has the name as its "lazy" attribute.
+func Hooks(logger *slog.Logger, name string, opts Options) lazy.Hooks {
+	start := level(opts.StartLevel, slog.LevelDebug)
+	finish := level(opts.FinishLevel, slog.LevelInfo)
+	fail := level(opts.FailLevel, slog.LevelError)
+	wait := level(opts.WaitLevel, slog.LevelWarn)
+	longWait := opts.LongWait
+	if longWait <= 0 {
+		longWait = time.Second
+	}
+	logger = logger.With("lazy", name)
+
+	return lazy.Hooks{
+		OnStart: func(ctx context.Context, attempt int) context.Context {
+			logger.Log(ctx, start, "lazy initialization started", "attempt", attempt)
+			return context.WithValue(ctx, attemptKey{}, attempt)
+		},
+		OnFinish: func(ctx context.Context, d time.Duration, err error) {
+			if err != nil {
+				attempt, _ := ctx.Value(attemptKey{}).(int)
+				logger.Log(ctx, fail, "lazy initialization failed",
+					"attempt", attempt, "duration", d, "error", err)
+				return
+			}
+			logger.Log(ctx, finish, "lazy initialization finished", "duration", d)
+		},
+		OnWaitDone: func(ctx context.Context, d time.Duration, shared bool, err error) {
+			if d < longWait {
+				return
+			}
+			args := []any{"duration", d, "shared", shared}
+			if err != nil {
+				args = append(args, "error", err)
+			}
+			logger.Log(ctx, wait, "long wait for lazy initialization", args...)
+		},
+	}
+}
+
+// level returns the level of l, or def if l is nil.
+func level(l slog.Leveler, def slog.Level) slog.Level {
+	if l == nil {
+		return def
+	}
+	return l.Level()
+}
@@ -0,0 +1,74 @@
+package lazyslog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/acycl/lazy"
+)
+
+// A syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHooks(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf syncBuffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+
+		fail := true
+		l := lazy.New(func(ctx context.Context) (int, error) {
+			time.Sleep(2 * time.Second)
+			if fail {
+				return 0, errors.New("refused")
+			}
+			return 1, nil
+		}, lazy.WithHooks(Hooks(logger, "db", Options{FailLevel: slog.LevelWarn})))
+
+		l.Get(context.Background())
+		fail = false
+		var wg sync.WaitGroup
+		wg.Go(func() { l.Get(context.Background()) })
+		synctest.Wait()
+		wg.Go(func() { l.Get(context.Background()) })
+		wg.Wait()
+
+		want := `level=DEBUG msg="lazy initialization started" lazy=db attempt=1
+level=WARN msg="lazy initialization failed" lazy=db attempt=1 duration=2s error=refused
+level=DEBUG msg="lazy initialization started" lazy=db attempt=2
+level=INFO msg="lazy initialization finished" lazy=db duration=2s
+level=WARN msg="long wait for lazy initialization" lazy=db duration=2s shared=true
+`
+		if got := buf.String(); got != want {
+			t.Fatalf("got log\n%s\nwant\n%s", got, want)
+		}
+	})
+}
@@ -0,0 +1,699 @@
+package lazy
+
+import (
+	"container/list"
+	"context"
+	"hash/maphash"
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Map is a collection of lazily initialized values, one per key. Each key's
+// value is loaded at most once successfully with the semantics of Lazy:
+// concurrent loads of the same key are deduplicated, failed loads are retried
+// on the next call, and successful values are cached. Options passed to NewMap
+// apply to each key independently. A Map only holds state for the keys being
+// loaded or cached, and for failed keys whose retry policy must be enforced.
+// A Map must be created with NewMap, NewMapWithOptions or NewMapWithHasher and
+// is safe for concurrent use by multiple goroutines.
+type Map[K, V any] struct {
+	load   func(context.Context, K) (V, error)
+	hasher Hasher[K]
+	opts   options
+	mo     MapOptions[K, V]
+
+	// refreshing limits the concurrent refreshes of WithRefreshAhead. It is
+	// nil if they are not limited.
+	refreshing chan struct{}
+	shards     []mapShard[K, V]
+
+	stop      chan struct{} // closed by Close
+	closeOnce sync.Once
+	closed    atomic.Bool
+}
+
+// A mapShard holds the entries of a Map whose key hashes map to it, so that
+// unrelated keys rarely contend for the same mutex.
+type mapShard[K, V any] struct {
+	capacity int
+	maxCost  int64
+	track    bool // whether removed values are reported to OnEvict
+
+	mu      sync.Mutex
+	entries map[uint64][]*mapEntry[K, V]
+	lru     list.List // of *mapEntry[K, V], most recently used first
+	len     int
+	cost    int64 // total cost of the loaded values
+
+	stats mapCounters
+
+	// evicted holds the values removed while mu is held, to be reported to
+	// the OnEvict callback once it is released.
+	evicted []eviction[K, V]
+
+	// watchers holds the channels returned by Watch, by key hash.
+	watchers map[uint64][]mapWatcher[K, V]
+}
+
+// A mapEntry holds the value for a single key of a Map. Its fields other than
+// key and hash are guarded by the mutex of its shard.
+type mapEntry[K, V any] struct {
+	key  K
+	hash uint64
+	l    *Lazy[stamped[V]]
+	elem *list.Element // nil once removed
+	cost int64         // cost of the loaded value
+
+	// removed is why e was removed from its shard, once elem is nil.
+	removed EvictReason
+
+	// stale is the expired value served while l reloads it, if any.
+	stale *stamped[V]
+
+	// hits counts the reads of the value of l, for WithRefreshAhead.
+	hits atomic.Int64
+
+	// refresh is the scheduled refresh of the value of l, if any.
+	refresh *time.Timer
+}
+
+// tagged reports whether the value of e or its stale value carries tag. It
+// must be called with the mutex of the shard of e held.
+func (e *mapEntry[K, V]) tagged(tag string) bool {
+	if e.stale != nil && slices.Contains(e.stale.tags, tag) {
+		return true
+	}
+	s, ok := e.l.peek()
+	return ok && slices.Contains(s.tags, tag)
+}
+
+// A Hasher hashes and compares keys of type K, allowing a Map to use keys that
+// are not comparable, such as slices or structs containing maps. Keys that are
+// equal must have the same hash.
+type Hasher[K any] interface {
+	Hash(K) uint64
+	Equal(K, K) bool
+}
+
+// MapOptions holds the settings of a Map that depend on its key and value
+// types, which an Option cannot carry. The zero value sets none of them.
+type MapOptions[K, V any] struct {
+	// MaxCost limits the total cost of the values of the Map, evicting the
+	// least recently used entries when a loaded value would exceed it. The
+	// cost of each value is computed once by Cost when it is loaded, so values
+	// of very different sizes can be bounded by weight rather than by count.
+	// As with WithCapacity, entries whose load is in progress are not evicted,
+	// and with WithShards each shard holds an equal share of the budget. Both
+	// must be set for the limit to apply.
+	MaxCost int64
+	Cost    func(V) int64
+
+	// OnEvict, if not nil, is called with the key, the value and the reason
+	// each time a loaded value is removed from the Map, whether it is
+	// evicted, expired or forgotten, so that resources it holds, such as
+	// connections or files, can be released. It is called without holding any
+	// lock of the Map, in the goroutine that removed the value, and may call
+	// methods of the Map.
+	OnEvict func(key K, value V, reason EvictReason)
+
+	// Backend, if not nil, is a second-level cache: a key that is not cached
+	// in memory is looked up in it before calling the load function, and a
+	// loaded value is written back to it, with the TTL of the value, if any.
+	// Forget also deletes the key from it, while eviction and expiry only
+	// affect memory. Errors of the backend do not fail a Get, which falls back
+	// to the load function; they are reported to OnBackendError instead, if
+	// it is not nil.
+	Backend        Backend[K, V]
+	OnBackendError func(error)
+
+	// KeyOptions, if not nil, returns options applied on top of the Map's own
+	// options when loading a key, so that keys backed by different upstreams
+	// can have different retry, backoff or panic policies. Only options that
+	// configure a Lazy take effect per key; those that configure the Map as a
+	// whole, such as WithCapacity, are ignored. It is called each time the key
+	// is loaded anew.
+	KeyOptions func(key K) []Option
+}
+
+// NewMap returns a Map whose values are loaded by calling load with their key.
+func NewMap[K comparable, V any](load func(context.Context, K) (V, error), opts ...Option) *Map[K, V] {
+	return NewMapWithOptions(load, MapOptions[K, V]{}, opts...)
+}
+
+// NewMapWithOptions is like NewMap but also applies mo.
+func NewMapWithOptions[K comparable, V any](load func(context.Context, K) (V, error), mo MapOptions[K, V], opts ...Option) *Map[K, V] {
+	return newMap(comparableHasher[K]{maphash.MakeSeed()}, load, mo, opts)
+}
+
+// NewMapWithHasher is like NewMap but uses h to hash and compare keys.
+func NewMapWithHasher[K, V any](h Hasher[K], load func(context.Context, K) (V, error), opts ...Option) *Map[K, V] {
+	return newMap(h, load, MapOptions[K, V]{}, opts)
+}
+
+// newMap returns a Map using h to hash and compare keys.
+func newMap[K, V any](h Hasher[K], load func(context.Context, K) (V, error), mo MapOptions[K, V], opts []Option) *Map[K, V] {
+	m := &Map[K, V]{
+		load:   load,
+		hasher: h,
+		opts:   newOptions(opts),
+		mo:     mo,
+		stop:   make(chan struct{}),
+	}
+	if mo.Cost == nil {
+		m.mo.MaxCost = 0
+	}
+	n := max(m.opts.shards, 1)
+	m.shards = make([]mapShard[K, V], n)
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.entries = make(map[uint64][]*mapEntry[K, V])
+		if c := m.opts.capacity; c > 0 {
+			sh.capacity = (c + n - 1) / n
+		}
+		sh.track = mo.OnEvict != nil
+		if c := m.mo.MaxCost; c > 0 {
+			sh.maxCost = (c + int64(n) - 1) / int64(n)
+		}
+	}
+	if n := m.opts.refreshConcurrency; n > 0 {
+		m.refreshing = make(chan struct{}, n)
+	}
+	if d := m.opts.janitor; d > 0 {
+		go m.janitor(d)
+	}
+	return m
+}
+
+// Close closes m for good, as during the shutdown of a program: all later calls
+// of Get and GetMany return ErrClosed without loading values. It stops the
+// background goroutine started by WithJanitor, if any, and cancels the
+// refreshes scheduled by WithRefreshAhead. The cached values are kept, to be
+// removed with ForgetAll if needed. Close may be called more than once.
+func (m *Map[K, V]) Close() {
+	m.closeOnce.Do(func() {
+		m.closed.Store(true)
+		close(m.stop)
+		for i := range m.shards {
+			sh := &m.shards[i]
+			sh.mu.Lock()
+			for elem := sh.lru.Front(); elem != nil; elem = elem.Next() {
+				if e := elem.Value.(*mapEntry[K, V]); e.refresh != nil {
+					e.refresh.Stop()
+					e.refresh = nil
+				}
+			}
+			sh.closeWatchersLocked()
+			sh.mu.Unlock()
+		}
+	})
+}
+
+// janitor removes expired values every interval until m is closed.
+func (m *Map[K, V]) janitor(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-t.C:
+			m.sweep(now)
+		}
+	}
+}
+
+// sweep removes the entries whose value has expired at now and may no longer
+// be served while it is revalidated.
+func (m *Map[K, V]) sweep(now time.Time) {
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.Lock()
+		for elem := sh.lru.Front(); elem != nil; {
+			e := elem.Value.(*mapEntry[K, V])
+			elem = elem.Next()
+			if e.stale != nil || e.l.busy() {
+				continue
+			}
+			if s, ok := e.l.peek(); ok && s.expired(now) && !m.servable(s, now) {
+				sh.removeLocked(e, EvictExpired)
+			}
+		}
+		m.unlock(sh)
+	}
+}
+
+// Len returns the number of keys held by m, whether their value is cached or
+// being loaded.
+func (m *Map[K, V]) Len() int {
+	n := 0
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.Lock()
+		n += sh.len
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+// ContainsKey reports whether m has a cached value for key that has not
+// expired. It neither loads the key nor counts as a use for eviction.
+func (m *Map[K, V]) ContainsKey(key K) bool {
+	h := m.hasher.Hash(key)
+	sh := m.shard(h)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	e := m.findLocked(sh, key, h)
+	if e == nil {
+		return false
+	}
+	s, ok := e.l.peek()
+	return ok && !s.expired(time.Now())
+}
+
+// All returns an iterator over the keys and values cached in m, excluding loads
+// in progress and expired values. It yields a consistent view of m taken when
+// iteration starts, so m may be used while iterating; changes made since are
+// not reflected.
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, rec := range m.records(time.Now()) {
+			if !yield(rec.Key, rec.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys cached in m, as All does.
+func (m *Map[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range m.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// shard returns the shard holding the keys with hash h.
+func (m *Map[K, V]) shard(h uint64) *mapShard[K, V] {
+	return &m.shards[h%uint64(len(m.shards))]
+}
+
+// Get returns the value for key, loading it if no value has been cached yet or
+// the cached value has expired. Only one goroutine reloads an expired key;
+// others wait for it as for the initial load, unless the Map serves stale
+// values while revalidating them.
+func (m *Map[K, V]) Get(ctx context.Context, key K) (V, error) {
+	if m.closed.Load() {
+		var zero V
+		return zero, ErrClosed
+	}
+	e, l, stale := m.entry(key)
+	c := &m.shard(e.hash).stats
+	if stale != nil {
+		if _, ok := l.peek(); !ok && m.servable(*stale, time.Now()) {
+			m.revalidate(ctx, l)
+			c.hits.Add(1)
+			return stale.value, nil
+		}
+	}
+
+	_, cached := l.peek()
+	if !cached {
+		c.misses.Add(1)
+	}
+	for reloaded := false; ; reloaded = true {
+		s, err := l.Get(ctx)
+		if err != nil {
+			m.discard(e, l)
+			var zero V
+			return zero, err
+		}
+		now := time.Now()
+		if reloaded || !s.expired(now) {
+			if !reloaded && s.refreshEarly(now, m.opts.earlyExpiration) {
+				if l = m.expire(e, l, true); l != nil {
+					m.revalidate(ctx, l)
+				}
+			}
+			if cached && !reloaded {
+				c.hits.Add(1)
+				m.hit(e, l, s)
+			}
+			return s.value, nil
+		}
+		if m.servable(s, now) {
+			if l = m.expire(e, l, true); l != nil {
+				m.revalidate(ctx, l)
+			}
+			if cached {
+				c.hits.Add(1)
+			}
+			return s.value, nil
+		}
+		if cached {
+			// The cached value expired: count a miss for the reload.
+			c.misses.Add(1)
+		}
+		if l = m.expire(e, l, false); l == nil {
+			e, l, _ = m.entry(key)
+		}
+	}
+}
+
+// GetMany returns the values for keys, in the same order, as Get would. Keys
+// with a cached value are served directly, while the others are loaded
+// concurrently, so that loads of a Map created with NewBatchMap are batched
+// together. Duplicate keys are loaded once. Loading errors are reported per
+// key in the Err field of the corresponding Result.
+func (m *Map[K, V]) GetMany(ctx context.Context, keys []K) []Result[V] {
+	results := make([]Result[V], len(keys))
+	if m.closed.Load() {
+		for i := range results {
+			results[i].Err = ErrClosed
+		}
+		return results
+	}
+	var wg sync.WaitGroup
+	now := time.Now()
+	for i, key := range keys {
+		e, l, _ := m.entry(key)
+		if s, ok := l.peek(); ok && !s.expired(now) {
+			m.shard(e.hash).stats.hits.Add(1)
+			m.hit(e, l, s)
+			results[i].Value = s.value
+			continue
+		}
+		wg.Go(func() {
+			results[i].Value, results[i].Err = m.Get(ctx, key)
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// servable reports whether the value s may still be served at now while it is
+// revalidated: until it expires, or until the end of the stale window after.
+func (m *Map[K, V]) servable(s stamped[V], now time.Time) bool {
+	return now.Before(s.expires.Add(m.opts.staleWhileRevalidate))
+}
+
+// revalidate starts reloading l in the background unless a load is already in
+// progress. The load runs under ctx without its cancellation.
+func (m *Map[K, V]) revalidate(ctx context.Context, l *Lazy[stamped[V]]) {
+	if l.busy() {
+		return
+	}
+	go l.TryGet(context.WithoutCancel(ctx))
+}
+
+// entry returns the entry holding the value for key, its current Lazy and the
+// stale value being revalidated, if any, creating the entry if needed.
+func (m *Map[K, V]) entry(key K) (*mapEntry[K, V], *Lazy[stamped[V]], *stamped[V]) {
+	h := m.hasher.Hash(key)
+	sh := m.shard(h)
+
+	sh.mu.Lock()
+	defer m.unlock(sh)
+	if e := m.findLocked(sh, key, h); e != nil {
+		sh.lru.MoveToFront(e.elem)
+		return e, e.l, e.stale
+	}
+	e := m.insertLocked(sh, key, h)
+	sh.evictLocked(e)
+	return e, e.l, nil
+}
+
+// findLocked returns the entry of sh for key, whose hash is h, or nil if there
+// is none. It must be called with sh.mu held.
+func (m *Map[K, V]) findLocked(sh *mapShard[K, V], key K, h uint64) *mapEntry[K, V] {
+	for _, e := range sh.entries[h] {
+		if m.hasher.Equal(e.key, key) {
+			return e
+		}
+	}
+	return nil
+}
+
+// insertLocked adds a new entry for key, whose hash is h, to sh as its most
+// recently used entry. It must be called with sh.mu held.
+func (m *Map[K, V]) insertLocked(sh *mapShard[K, V], key K, h uint64) *mapEntry[K, V] {
+	e := &mapEntry[K, V]{key: key, hash: h}
+	e.l = m.newLazy(e)
+	e.elem = sh.lru.PushFront(e)
+	sh.entries[h] = append(sh.entries[h], e)
+	sh.len++
+	return e
+}
+
+// discard removes e if its Lazy is still l and l holds no state worth
+// keeping after a failed load, so that keys whose loads fail do not hold on to
+// memory.
+func (m *Map[K, V]) discard(e *mapEntry[K, V], l *Lazy[stamped[V]]) {
+	sh := m.shard(e.hash)
+	sh.mu.Lock()
+	defer m.unlock(sh)
+	if e.elem != nil && e.l == l && e.stale == nil && l.unused() {
+		sh.removeLocked(e, EvictForgotten)
+	}
+}
+
+// expire replaces l, whose value has expired, as the Lazy of e so that the
+// next Get reloads the key, and returns the replacement. If keepStale is true,
+// the expired value is kept to be served during the reload. If another
+// goroutine has already replaced l, its replacement is returned instead. It
+// returns nil if e has been removed from the Map.
+func (m *Map[K, V]) expire(e *mapEntry[K, V], l *Lazy[stamped[V]], keepStale bool) *Lazy[stamped[V]] {
+	sh := m.shard(e.hash)
+	sh.mu.Lock()
+	defer m.unlock(sh)
+	if e.elem == nil {
+		return nil
+	}
+	if e.l != l {
+		return e.l
+	}
+	sh.dropStaleLocked(e)
+	if s, ok := l.peek(); ok {
+		if keepStale {
+			e.stale = &s
+		} else {
+			sh.evictedLocked(e, s, EvictExpired)
+		}
+	}
+	if e.refresh != nil {
+		e.refresh.Stop()
+		e.refresh = nil
+	}
+	e.l = m.newLazy(e)
+	e.hits.Store(0)
+	return e.l
+}
+
+// newLazy returns a Lazy that loads the value for e.
+func (m *Map[K, V]) newLazy(e *mapEntry[K, V]) *Lazy[stamped[V]] {
+	var l *Lazy[stamped[V]]
+	l = newLazy(noCleanup(func(ctx context.Context) (stamped[V], error) {
+		s, err := loadStamped(ctx, m.opts.ttl, func(ctx context.Context) (V, error) {
+			return m.loadKey(ctx, e.key)
+		})
+		if err == nil {
+			m.loaded(e, l, s)
+		}
+		return s, err
+	}), m.lazyOptions(e.key), &m.shard(e.hash).stats.lazy)
+	return l
+}
+
+// lazyOptions returns the options of the Lazy loading key.
+func (m *Map[K, V]) lazyOptions(key K) options {
+	if m.mo.KeyOptions == nil {
+		return m.opts
+	}
+	o := m.opts
+	for _, opt := range m.mo.KeyOptions(key) {
+		opt(&o)
+	}
+	return o
+}
+
+// loaded records s as the value loaded by l for e: it drops the stale value
+// that s replaces, if any, sends its value to the watchers of the key, and
+// evicts entries while the shard of e is over its cost budget. If e was
+// removed or l replaced during the load, s is recorded as removed at once, so
+// that its cleanup function still runs.
+func (m *Map[K, V]) loaded(e *mapEntry[K, V], l *Lazy[stamped[V]], s stamped[V]) {
+	sh := m.shard(e.hash)
+	sh.mu.Lock()
+	defer m.unlock(sh)
+	switch {
+	case e.elem == nil:
+		sh.evictedLocked(e, s, e.removed)
+		return
+	case e.l != l:
+		sh.evictedLocked(e, s, EvictExpired)
+		return
+	}
+	sh.dropStaleLocked(e)
+	m.cachedLocked(sh, e, s.value)
+	if m.mo.Cost != nil {
+		c := m.mo.Cost(s.value)
+		sh.cost += c - e.cost
+		e.cost = c
+		sh.evictLocked(nil)
+	}
+}
+
+// Forget removes the cached value for key, if any, so that the next Get loads it
+// anew. Callers already waiting for a load of key are not affected. With a
+// Backend, the key is also deleted from it.
+func (m *Map[K, V]) Forget(key K) {
+	if m.mo.Backend != nil {
+		if err := m.mo.Backend.Delete(context.Background(), key); err != nil {
+			m.backendError(err)
+		}
+	}
+
+	h := m.hasher.Hash(key)
+	sh := m.shard(h)
+
+	sh.mu.Lock()
+	defer m.unlock(sh)
+	if e := m.findLocked(sh, key, h); e != nil {
+		sh.removeLocked(e, EvictForgotten)
+	}
+}
+
+// forgetValue removes the entry for key if its value is v, which is comparable,
+// so that a value replaced concurrently is kept.
+func (m *Map[K, V]) forgetValue(key K, v V) {
+	h := m.hasher.Hash(key)
+	sh := m.shard(h)
+
+	sh.mu.Lock()
+	defer m.unlock(sh)
+	if e := m.findLocked(sh, key, h); e != nil {
+		if s, ok := e.l.peek(); ok && any(s.value) == any(v) {
+			sh.removeLocked(e, EvictForgotten)
+		}
+	}
+}
+
+// InvalidateTag removes the cached values that were given tag with Tag when
+// they were loaded, including stale values being revalidated, and returns how
+// many keys were removed. With a Backend, the keys are also deleted from the
+// backend. Loads in progress are not affected.
+func (m *Map[K, V]) InvalidateTag(tag string) int {
+	var keys []K
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.Lock()
+		for elem := sh.lru.Front(); elem != nil; {
+			e := elem.Value.(*mapEntry[K, V])
+			elem = elem.Next()
+			if e.tagged(tag) {
+				sh.removeLocked(e, EvictInvalidated)
+				keys = append(keys, e.key)
+			}
+		}
+		m.unlock(sh)
+	}
+	if m.mo.Backend != nil {
+		for _, key := range keys {
+			if err := m.mo.Backend.Delete(context.Background(), key); err != nil {
+				m.backendError(err)
+			}
+		}
+	}
+	return len(keys)
+}
+
+// ForgetAll removes all cached values from m. It does not affect the backend
+// set in MapOptions.
+func (m *Map[K, V]) ForgetAll() {
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.Lock()
+		for elem := sh.lru.Front(); elem != nil; elem = elem.Next() {
+			e := elem.Value.(*mapEntry[K, V])
+			sh.dropValuesLocked(e, EvictForgotten)
+			e.elem = nil
+			e.removed = EvictForgotten
+		}
+		clear(sh.entries)
+		sh.lru.Init()
+		sh.len = 0
+		sh.cost = 0
+		m.unlock(sh)
+	}
+}
+
+// evictLocked removes least recently used entries other than keep, if not nil,
+// while the shard is over capacity or over its cost budget. Entries whose load
+// is in progress are skipped so that concurrent loads of their keys stay
+// deduplicated; the shard may exceed its capacity until they finish. The entry
+// just inserted for a caller about to load it is kept for the same reason. It
+// must be called with sh.mu held.
+func (sh *mapShard[K, V]) evictLocked(keep *mapEntry[K, V]) {
+	for elem := sh.lru.Back(); elem != nil && sh.overLocked(); {
+		e := elem.Value.(*mapEntry[K, V])
+		elem = elem.Prev()
+		if e == keep || e.l.busy() {
+			continue
+		}
+		sh.removeLocked(e, EvictCapacity)
+		sh.stats.evictions.Add(1)
+	}
+}
+
+// overLocked reports whether the shard holds more entries or more cost than it
+// may. It must be called with sh.mu held.
+func (sh *mapShard[K, V]) overLocked() bool {
+	return sh.capacity > 0 && sh.len > sh.capacity ||
+		sh.maxCost > 0 && sh.cost > sh.maxCost
+}
+
+// removeLocked removes e from the shard for reason. It must be called with
+// sh.mu held.
+func (sh *mapShard[K, V]) removeLocked(e *mapEntry[K, V], reason EvictReason) {
+	sh.dropValuesLocked(e, reason)
+	if e.refresh != nil {
+		e.refresh.Stop()
+		e.refresh = nil
+	}
+	bucket := sh.entries[e.hash]
+	for i, other := range bucket {
+		if other == e {
+			bucket = append(bucket[:i], bucket[i+1:]...)
+			break
+		}
+	}
+	if len(bucket) == 0 {
+		delete(sh.entries, e.hash)
+	} else {
+		sh.entries[e.hash] = bucket
+	}
+	sh.lru.Remove(e.elem)
+	e.elem = nil
+	e.removed = reason
+	sh.len--
+	sh.cost -= e.cost
+}
+
+// comparableHasher is the Hasher for comparable keys.
+type comparableHasher[K comparable] struct {
+	seed maphash.Seed
+}
+
+func (h comparableHasher[K]) Hash(key K) uint64 {
+	return maphash.Comparable(h.seed, key)
+}
+
+func (h comparableHasher[K]) Equal(a, b K) bool {
+	return a == b
+}
@@ -0,0 +1,592 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestMap_LoadsEachKeyOnce(t *testing.T) {
+	var calls atomic.Int32
+
+	m := NewMap(func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		return len(key), nil
+	})
+
+	for range 2 {
+		for _, key := range []string{"a", "bb", "ccc"} {
+			v, err := m.Get(context.Background(), key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v != len(key) {
+				t.Fatalf("Get(%q) = %d, want %d", key, v, len(key))
+			}
+		}
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("load called %d times, want 3", got)
+	}
+}
+
+func TestMap_DeduplicatesConcurrentLoads(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		proceed := make(chan struct{})
+
+		m := NewMap(func(ctx context.Context, key int) (int, error) {
+			calls.Add(1)
+			<-proceed
+			return key * 10, nil
+		})
+
+		results := make(chan int, 3)
+		for range 3 {
+			go func() {
+				v, _ := m.Get(context.Background(), 1)
+				results <- v
+			}()
+		}
+		synctest.Wait()
+		close(proceed)
+
+		for range 3 {
+			if v := <-results; v != 10 {
+				t.Errorf("got %d, want 10", v)
+			}
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("load called %d times, want 1", got)
+		}
+	})
+}
+
+func TestMap_RetriesPerKey(t *testing.T) {
+	var calls atomic.Int32
+	errFail := errors.New("fail")
+
+	m := NewMap(func(ctx context.Context, key string) (string, error) {
+		if key == "bad" && calls.Add(1) == 1 {
+			return "", errFail
+		}
+		return key, nil
+	})
+
+	if _, err := m.Get(context.Background(), "bad"); !errors.Is(err, errFail) {
+		t.Fatalf("got error %v, want %v", err, errFail)
+	}
+	if v, err := m.Get(context.Background(), "good"); err != nil || v != "good" {
+		t.Fatalf("got (%q, %v), want (%q, nil)", v, err, "good")
+	}
+	if v, err := m.Get(context.Background(), "bad"); err != nil || v != "bad" {
+		t.Fatalf("got (%q, %v), want (%q, nil)", v, err, "bad")
+	}
+}
+
+func TestMap_OptionsApplyPerKey(t *testing.T) {
+	errFail := errors.New("fail")
+
+	m := NewMap(func(ctx context.Context, key string) (int, error) {
+		return 0, errFail
+	}, WithMaxAttempts(1))
+
+	if _, err := m.Get(context.Background(), "a"); !errors.Is(err, ErrFrozen) {
+		t.Fatalf("got error %v, want %v", err, ErrFrozen)
+	}
+	if _, err := m.Get(context.Background(), "b"); !errors.Is(err, ErrFrozen) {
+		t.Fatalf("got error %v, want %v", err, ErrFrozen)
+	}
+}
+
+// sliceHasher is a Hasher for []string keys.
+type sliceHasher struct {
+	seed maphash.Seed
+}
+
+func (h sliceHasher) Hash(key []string) uint64 {
+	var mh maphash.Hash
+	mh.SetSeed(h.seed)
+	for _, s := range key {
+		mh.WriteString(s)
+		mh.WriteByte(0)
+	}
+	return mh.Sum64()
+}
+
+func (h sliceHasher) Equal(a, b []string) bool {
+	return slices.Equal(a, b)
+}
+
+func TestNewMapWithHasher(t *testing.T) {
+	var calls atomic.Int32
+
+	m := NewMapWithHasher(sliceHasher{maphash.MakeSeed()}, func(ctx context.Context, key []string) (string, error) {
+		calls.Add(1)
+		return strings.Join(key, "/"), nil
+	})
+
+	for range 2 {
+		if v, _ := m.Get(context.Background(), []string{"a", "b"}); v != "a/b" {
+			t.Fatalf("got %q, want %q", v, "a/b")
+		}
+		if v, _ := m.Get(context.Background(), []string{"ab"}); v != "ab" {
+			t.Fatalf("got %q, want %q", v, "ab")
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("load called %d times, want 2", got)
+	}
+}
+
+// collidingHasher hashes every key to the same value.
+type collidingHasher struct{}
+
+func (collidingHasher) Hash(int) uint64     { return 0 }
+func (collidingHasher) Equal(a, b int) bool { return a == b }
+
+func TestNewMapWithHasher_Collisions(t *testing.T) {
+	m := NewMapWithHasher(collidingHasher{}, func(ctx context.Context, key int) (int, error) {
+		return key, nil
+	})
+
+	for key := range 5 {
+		if v, _ := m.Get(context.Background(), key); v != key {
+			t.Fatalf("Get(%d) = %d", key, v)
+		}
+	}
+}
+
+func TestWithCapacity_EvictsLeastRecentlyUsed(t *testing.T) {
+	var calls atomic.Int32
+
+	m := NewMap(func(ctx context.Context, key string) (string, error) {
+		calls.Add(1)
+		return key, nil
+	}, WithCapacity(2))
+
+	ctx := context.Background()
+	m.Get(ctx, "a")
+	m.Get(ctx, "b")
+	m.Get(ctx, "a") // "b" is now least recently used.
+	m.Get(ctx, "c") // Evicts "b".
+
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("load called %d times, want 3", got)
+	}
+	m.Get(ctx, "a")
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("load called %d times after reading a cached key, want 3", got)
+	}
+	m.Get(ctx, "b")
+	if got := calls.Load(); got != 4 {
+		t.Fatalf("load called %d times after reading an evicted key, want 4", got)
+	}
+}
+
+func TestWithCapacity_KeepsInFlightLoads(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		proceed := make(chan struct{})
+
+		m := NewMap(func(ctx context.Context, key string) (string, error) {
+			calls.Add(1)
+			if key == "slow" {
+				<-proceed
+			}
+			return key, nil
+		}, WithCapacity(1))
+
+		results := make(chan string, 2)
+		go func() {
+			v, _ := m.Get(context.Background(), "slow")
+			results <- v
+		}()
+		synctest.Wait()
+
+		// Loading another key must not evict the in-flight load.
+		m.Get(context.Background(), "fast")
+		go func() {
+			v, _ := m.Get(context.Background(), "slow")
+			results <- v
+		}()
+		synctest.Wait()
+
+		close(proceed)
+		for range 2 {
+			if v := <-results; v != "slow" {
+				t.Fatalf("got %q, want %q", v, "slow")
+			}
+		}
+		if got := calls.Load(); got != 2 {
+			t.Fatalf("load called %d times, want 2", got)
+		}
+	})
+}
+
+func TestWithCapacity_DeduplicatesLoadsOverCapacity(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		proceed := make(chan struct{})
+
+		m := NewMap(func(ctx context.Context, key string) (string, error) {
+			if key == "b" {
+				calls.Add(1)
+			}
+			<-proceed
+			return key, nil
+		}, WithCapacity(1), WithShards(1))
+
+		go m.Get(context.Background(), "a")
+		synctest.Wait()
+
+		// The shard is over capacity with "a" loading: the entry of "b" must
+		// not be evicted as soon as it is inserted.
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Go(func() { m.Get(context.Background(), "b") })
+		}
+		synctest.Wait()
+		close(proceed)
+		wg.Wait()
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("load of b called %d times, want 1", got)
+		}
+	})
+}
+
+func TestMapOptions_MaxCost(t *testing.T) {
+	var calls atomic.Int32
+
+	m := NewMapWithOptions(func(ctx context.Context, key string) (string, error) {
+		calls.Add(1)
+		return key, nil
+	}, MapOptions[string, string]{
+		MaxCost: 10,
+		Cost:    func(v string) int64 { return int64(len(v)) },
+	})
+
+	ctx := context.Background()
+	m.Get(ctx, "aaaa")
+	m.Get(ctx, "bbbb")
+	m.Get(ctx, "aaaa")   // "bbbb" is now least recently used.
+	m.Get(ctx, "cccccc") // Exceeds the budget, evicting only "bbbb".
+
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("load called %d times, want 3", got)
+	}
+	m.Get(ctx, "aaaa")
+	m.Get(ctx, "cccccc")
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("load called %d times after reading cached keys, want 3", got)
+	}
+	m.Get(ctx, "bbbb")
+	if got := calls.Load(); got != 4 {
+		t.Fatalf("load called %d times after reading an evicted key, want 4", got)
+	}
+}
+
+func TestWithShards(t *testing.T) {
+	var calls atomic.Int32
+
+	m := NewMap(func(ctx context.Context, key int) (int, error) {
+		calls.Add(1)
+		return key * 2, nil
+	}, WithShards(8))
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Go(func() {
+			for key := range 100 {
+				if v, err := m.Get(context.Background(), key); err != nil || v != key*2 {
+					t.Errorf("goroutine %d: Get(%d) = (%d, %v)", g, key, v, err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 100 {
+		t.Fatalf("load called %d times, want 100", got)
+	}
+}
+
+func TestWithShards_Capacity(t *testing.T) {
+	m := NewMap(func(ctx context.Context, key int) (int, error) {
+		return key, nil
+	}, WithShards(4), WithCapacity(10))
+
+	for key := range 100 {
+		m.Get(context.Background(), key)
+	}
+
+	total := 0
+	for i := range m.shards {
+		sh := &m.shards[i]
+		if sh.len > sh.capacity {
+			t.Errorf("shard %d holds %d entries, want at most %d", i, sh.len, sh.capacity)
+		}
+		total += sh.len
+	}
+	if total > 12 {
+		t.Fatalf("map holds %d entries, want at most 12", total)
+	}
+}
+
+func BenchmarkMap_Get(b *testing.B) {
+	for _, shards := range []int{1, 16} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			m := NewMap(func(ctx context.Context, key int) (int, error) {
+				return key, nil
+			}, WithShards(shards))
+			b.RunParallel(func(pb *testing.PB) {
+				for key := 0; pb.Next(); key++ {
+					m.Get(context.Background(), key%1024)
+				}
+			})
+		})
+	}
+}
+
+func TestMap_Forget(t *testing.T) {
+	var calls atomic.Int32
+
+	m := NewMap(func(ctx context.Context, key string) (int32, error) {
+		return calls.Add(1), nil
+	})
+
+	ctx := context.Background()
+	m.Get(ctx, "a")
+	m.Get(ctx, "b")
+	m.Forget("a")
+	m.Forget("missing")
+
+	if v, _ := m.Get(ctx, "a"); v != 3 {
+		t.Fatalf("got %d for forgotten key, want 3", v)
+	}
+	if v, _ := m.Get(ctx, "b"); v != 2 {
+		t.Fatalf("got %d for retained key, want 2", v)
+	}
+}
+
+func TestMap_ForgetAll(t *testing.T) {
+	var calls atomic.Int32
+
+	m := NewMap(func(ctx context.Context, key string) (int32, error) {
+		return calls.Add(1), nil
+	}, WithShards(4))
+
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		m.Get(ctx, key)
+	}
+	m.ForgetAll()
+	for _, key := range []string{"a", "b", "c"} {
+		m.Get(ctx, key)
+	}
+	if got := calls.Load(); got != 6 {
+		t.Fatalf("load called %d times, want 6", got)
+	}
+}
+
+func TestMap_GetMany(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errOdd := errors.New("odd key")
+		var calls atomic.Int32
+		m := NewMap(func(ctx context.Context, key int) (int, error) {
+			calls.Add(1)
+			time.Sleep(time.Second)
+			if key%2 == 1 {
+				return 0, errOdd
+			}
+			return key * 10, nil
+		})
+		if _, err := m.Get(context.Background(), 0); err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		results := m.GetMany(context.Background(), []int{0, 1, 2, 2, 4})
+		// Missing keys are loaded concurrently.
+		if d := time.Since(start); d != time.Second {
+			t.Fatalf("GetMany took %v, want %v", d, time.Second)
+		}
+		if got := calls.Load(); got != 4 {
+			t.Fatalf("got %d calls, want 4", got)
+		}
+
+		want := []Result[int]{{Value: 0}, {Err: errOdd}, {Value: 20}, {Value: 20}, {Value: 40}}
+		for i, r := range results {
+			if r.Value != want[i].Value || !errors.Is(r.Err, want[i].Err) {
+				t.Fatalf("result %d = %+v, want %+v", i, r, want[i])
+			}
+		}
+	})
+}
+
+func TestMap_InvalidateTag(t *testing.T) {
+	var calls atomic.Int32
+	m := NewMap(func(ctx context.Context, key string) (string, error) {
+		calls.Add(1)
+		tenant, _, _ := strings.Cut(key, "/")
+		Tag(ctx, "tenant:"+tenant, "all")
+		return key, nil
+	})
+
+	ctx := context.Background()
+	for _, key := range []string{"1/a", "1/b", "2/a"} {
+		m.Get(ctx, key)
+	}
+	if n := m.InvalidateTag("tenant:1"); n != 2 {
+		t.Fatalf("InvalidateTag removed %d keys, want 2", n)
+	}
+	if n := m.InvalidateTag("tenant:1"); n != 0 {
+		t.Fatalf("second InvalidateTag removed %d keys, want 0", n)
+	}
+
+	for _, key := range []string{"1/a", "1/b", "2/a"} {
+		m.Get(ctx, key)
+	}
+	if got := calls.Load(); got != 5 {
+		t.Fatalf("load called %d times, want 5", got)
+	}
+	if n := m.InvalidateTag("all"); n != 3 {
+		t.Fatalf("InvalidateTag removed %d keys, want 3", n)
+	}
+}
+
+func TestMap_All(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		m := NewMap(func(ctx context.Context, key int) (int, error) {
+			if key == 3 {
+				return 0, errors.New("failed")
+			}
+			if key == 4 {
+				SetTTL(ctx, time.Second)
+			}
+			return key * 10, nil
+		}, WithShards(4))
+
+		ctx := context.Background()
+		for key := range 5 {
+			m.Get(ctx, key)
+		}
+		time.Sleep(time.Second) // Expires key 4.
+
+		got := make(map[int]int)
+		for k, v := range m.All() {
+			m.Forget(k) // The Map may be used while iterating.
+			got[k] = v
+		}
+		want := map[int]int{0: 0, 1: 10, 2: 20}
+		if !maps.Equal(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		if keys := slices.Collect(m.Keys()); len(keys) != 0 {
+			t.Fatalf("got keys %v after forgetting them, want none", keys)
+		}
+	})
+}
+
+func TestMap_ReleasesFailedKeys(t *testing.T) {
+	errFailed := errors.New("failed")
+	m := NewMap(func(ctx context.Context, key int) (int, error) {
+		return 0, errFailed
+	})
+	ctx := context.Background()
+	for key := range 100 {
+		m.Get(ctx, key)
+	}
+	if n := m.shards[0].len; n != 0 {
+		t.Fatalf("got %d entries after failed loads, want 0", n)
+	}
+
+	// Failures are kept when the retry policy depends on them.
+	m = NewMap(func(ctx context.Context, key int) (int, error) {
+		return 0, errFailed
+	}, WithMaxAttempts(1))
+	m.Get(ctx, 0)
+	if _, err := m.Get(ctx, 0); !errors.Is(err, ErrFrozen) {
+		t.Fatalf("got error %v, want %v", err, ErrFrozen)
+	}
+}
+
+func TestMap_LenContainsKey(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		proceed := make(chan struct{})
+		m := NewMap(func(ctx context.Context, key string) (string, error) {
+			if key == "slow" {
+				<-proceed
+			}
+			if key == "short" {
+				SetTTL(ctx, time.Second)
+			}
+			return key, nil
+		})
+
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		m.Get(ctx, "short")
+		go m.Get(ctx, "slow")
+		synctest.Wait()
+
+		if n := m.Len(); n != 3 {
+			t.Fatalf("got Len %d, want 3", n)
+		}
+		if !m.ContainsKey("a") || !m.ContainsKey("short") {
+			t.Fatal("ContainsKey reported cached keys as missing")
+		}
+		if m.ContainsKey("slow") || m.ContainsKey("missing") {
+			t.Fatal("ContainsKey reported keys without a value as present")
+		}
+		time.Sleep(time.Second)
+		if m.ContainsKey("short") {
+			t.Fatal("ContainsKey reported an expired key as present")
+		}
+		close(proceed)
+		synctest.Wait()
+	})
+}
+
+func TestMapOptions_KeyOptions(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls sync.Map // of string to *atomic.Int32
+		m := NewMapWithOptions(func(ctx context.Context, key string) (int, error) {
+			n, _ := calls.LoadOrStore(key, new(atomic.Int32))
+			n.(*atomic.Int32).Add(1)
+			return 0, errors.New("failed")
+		}, MapOptions[string, int]{
+			KeyOptions: func(key string) []Option {
+				if strings.HasPrefix(key, "slow/") {
+					return []Option{WithBackoff(time.Minute, 0, 0)}
+				}
+				return nil
+			},
+		})
+
+		ctx := context.Background()
+		for range 3 {
+			m.Get(ctx, "slow/a")
+			m.Get(ctx, "fast/a")
+		}
+		count := func(key string) int32 {
+			n, _ := calls.Load(key)
+			return n.(*atomic.Int32).Load()
+		}
+		if got := count("slow/a"); got != 1 {
+			t.Fatalf("load of a key with a backoff called %d times, want 1", got)
+		}
+		if got := count("fast/a"); got != 3 {
+			t.Fatalf("load of a key without a backoff called %d times, want 3", got)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package lazy
+
+import "context"
+
+// Memo1 memoizes f by its argument: f is called at most once successfully per
+// distinct argument, with the semantics of Map.
+func Memo1[A comparable, T any](f func(context.Context, A) (T, error), opts ...Option) func(context.Context, A) (T, error) {
+	return NewMap(f, opts...).Get
+}
+
+// Memo2 is like Memo1 for functions of two arguments.
+func Memo2[A, B comparable, T any](f func(context.Context, A, B) (T, error), opts ...Option) func(context.Context, A, B) (T, error) {
+	m := NewMap(func(ctx context.Context, k pair[A, B]) (T, error) {
+		return f(ctx, k.a, k.b)
+	}, opts...)
+	return func(ctx context.Context, a A, b B) (T, error) {
+		return m.Get(ctx, pair[A, B]{a, b})
+	}
+}
+
+// pair is the key type of Memo2.
+type pair[A, B comparable] struct {
+	a A
+	b B
+}
+
+// FuncBy is like Func but keeps a separate value for each key derived from the
+// caller's context by key, such as a tenant ID, so that one declared function
+// maintains a value per tenant without its call sites extracting the tenant
+// themselves. f is called with the context of the first caller for each key.
+// The values are kept with the semantics of Map, to which opts apply.
+func FuncBy[K comparable, T any](key func(context.Context) K, f func(context.Context) (T, error), opts ...Option) func(context.Context) (T, error) {
+	m := NewMap(func(ctx context.Context, _ K) (T, error) {
+		return f(ctx)
+	}, opts...)
+	return func(ctx context.Context) (T, error) {
+		return m.Get(ctx, key(ctx))
+	}
+}
+
+// ContextValue returns a function, for use with FuncBy, that returns the value
+// of ctx for key, or the zero value of K if ctx has no value of type K for key.
+func ContextValue[K any](key any) func(context.Context) K {
+	return func(ctx context.Context) K {
+		v, _ := ctx.Value(key).(K)
+		return v
+	}
+}
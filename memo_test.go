@@ -0,0 +1,70 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMemo1(t *testing.T) {
+	var calls atomic.Int32
+
+	square := Memo1(func(ctx context.Context, n int) (int, error) {
+		calls.Add(1)
+		return n * n, nil
+	})
+
+	for range 2 {
+		for n := range 3 {
+			if v, err := square(context.Background(), n); err != nil || v != n*n {
+				t.Fatalf("square(%d) = (%d, %v), want (%d, nil)", n, v, err, n*n)
+			}
+		}
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("function called %d times, want 3", got)
+	}
+}
+
+func TestMemo2(t *testing.T) {
+	var calls atomic.Int32
+
+	join := Memo2(func(ctx context.Context, a string, b int) (string, error) {
+		calls.Add(1)
+		return fmt.Sprintf("%s-%d", a, b), nil
+	})
+
+	for range 2 {
+		if v, _ := join(context.Background(), "x", 1); v != "x-1" {
+			t.Fatalf("got %q, want %q", v, "x-1")
+		}
+		if v, _ := join(context.Background(), "x", 2); v != "x-2" {
+			t.Fatalf("got %q, want %q", v, "x-2")
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("function called %d times, want 2", got)
+	}
+}
+
+func TestFuncBy(t *testing.T) {
+	type tenantKey struct{}
+	var calls atomic.Int32
+	f := FuncBy(ContextValue[string](tenantKey{}), func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return "config of " + ctx.Value(tenantKey{}).(string), nil
+	})
+
+	for range 2 {
+		for _, tenant := range []string{"a", "b"} {
+			ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+			if v, _ := f(ctx); v != "config of "+tenant {
+				t.Fatalf("got %q, want %q", v, "config of "+tenant)
+			}
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("f called %d times, want 2", got)
+	}
+}
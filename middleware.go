@@ -0,0 +1,16 @@
+package lazy
+
+import "net/http"
+
+// Middleware returns a handler that installs a new scope for InCtx into each
+// request's context before calling next, so that handlers and the helpers they
+// call can share once-per-request computations. The scope is closed when next
+// returns, releasing its values even if the request's context outlives the
+// request.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewScope(r.Context())
+		defer ctx.Value(scopeKey{}).(*scope).close()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,42 @@
+package lazy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	currentUser := func(ctx context.Context) (string, error) {
+		return InCtx(ctx, userKey{}, func(ctx context.Context) (string, error) {
+			calls.Add(1)
+			return "alice", nil
+		})
+	}
+
+	var reqCtx context.Context
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqCtx = r.Context()
+		for range 3 {
+			if user, err := currentUser(r.Context()); err != nil || user != "alice" {
+				t.Errorf("got (%q, %v), want (%q, nil)", user, err, "alice")
+			}
+		}
+	}))
+
+	for want := int32(1); want <= 2; want++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		if got := calls.Load(); got != want {
+			t.Fatalf("function called %d times, want %d", got, want)
+		}
+	}
+
+	// The scope is closed once the request has been served.
+	currentUser(reqCtx)
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("function called %d times after the request, want 3", got)
+	}
+}
@@ -0,0 +1,27 @@
+package lazy
+
+import "context"
+
+// FromOnce adapts once, typically the result of sync.OnceValues, to the
+// signature returned by Func. Because once caches its error as well as its
+// value, a failed initialization is never retried. The context is only checked
+// before calling once; a call that is already blocked waiting for once to
+// finish cannot be cancelled.
+func FromOnce[T any](once func() (T, error)) func(context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		if ctx.Err() != nil {
+			var zero T
+			return zero, context.Cause(ctx)
+		}
+		return once()
+	}
+}
+
+// ToOnce adapts f, typically the result of Func, to the signature returned by
+// sync.OnceValues. Calls use context.Background. Unlike sync.OnceValues, errors
+// are not cached: if f retries on error, so does the returned function.
+func ToOnce[T any](f func(context.Context) (T, error)) func() (T, error) {
+	return func() (T, error) {
+		return f(context.Background())
+	}
+}
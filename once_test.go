@@ -0,0 +1,69 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFromOnce_ErrorIsCached(t *testing.T) {
+	var calls atomic.Int32
+	errFail := errors.New("fail")
+
+	f := FromOnce(sync.OnceValues(func() (int, error) {
+		calls.Add(1)
+		return 0, errFail
+	}))
+
+	for range 2 {
+		if _, err := f(context.Background()); !errors.Is(err, errFail) {
+			t.Fatalf("got error %v, want %v", err, errFail)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("function called %d times, want 1", got)
+	}
+}
+
+func TestFromOnce_CancelledContext(t *testing.T) {
+	var calls atomic.Int32
+
+	f := FromOnce(sync.OnceValues(func() (int, error) {
+		calls.Add(1)
+		return 1, nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := f(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	if got := calls.Load(); got != 0 {
+		t.Fatalf("function called %d times, want 0", got)
+	}
+}
+
+func TestToOnce_PreservesRetry(t *testing.T) {
+	var calls atomic.Int32
+
+	once := ToOnce(Func(func(ctx context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			return 0, errors.New("temporary failure")
+		}
+		return 5, nil
+	}))
+
+	if _, err := once(); err == nil {
+		t.Fatal("expected error on first call")
+	}
+	result, err := once()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 5 {
+		t.Fatalf("got %d, want 5", result)
+	}
+}
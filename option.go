@@ -0,0 +1,384 @@
+package lazy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// An Option configures the behavior of a Lazy, or of a Map and its values.
+// WithCapacity, WithTTL, WithStaleWhileRevalidate, WithEarlyExpiration,
+// WithRefreshAhead, WithJanitor, WithCodec and WithShards configure the caching
+// of a Map and have no effect on a Lazy, while WithCollectCleanup has no effect
+// on a Map. The settings of a Map that depend on its key and value types are
+// given as MapOptions instead.
+type Option func(*options)
+
+type options struct {
+	propagatePanics      bool
+	recoverPanics        bool
+	maxAttempts          int
+	backoff              backoff
+	attemptTimeout       time.Duration
+	retryBudget          time.Duration
+	permanent            func(error) bool
+	detached             bool
+	handoff              bool
+	checkOnCancel        bool
+	mergedContext        bool
+	maxWait              time.Duration
+	maxWaiters           int
+	hedgeDelay           time.Duration
+	hedgeAttempts        int
+	minDeadline          time.Duration
+	debug                bool
+	sharedResults        bool
+	capacity             int
+	ttl                  time.Duration
+	staleWhileRevalidate time.Duration
+	shards               int
+	janitor              time.Duration
+	earlyExpiration      float64
+	codec                Codec
+	refreshWindow        time.Duration
+	refreshHits          int
+	refreshConcurrency   int
+	collectCleanup       bool
+	hooks                *Hooks
+	name                 string
+	site                 string
+	slowThreshold        time.Duration
+	onSlow               func(time.Duration, []byte)
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithDebug enables checks that panic rather than return an error, so that
+// programming mistakes such as reentrant initialization surface at their
+// source during development.
+func WithDebug() Option {
+	return func(o *options) {
+		o.debug = true
+	}
+}
+
+// WithPanicPropagation makes panics sticky, matching the semantics of
+// sync.OnceFunc: if the initialization function panics, all current and future
+// callers panic with the same value, and the function is never called again.
+func WithPanicPropagation() Option {
+	return func(o *options) {
+		o.propagatePanics = true
+	}
+}
+
+// WithPanicRecovery converts a panic in the initialization function into a
+// *PanicError, which is returned to the panicking caller and to every caller
+// that was waiting for that initialization. Nothing is cached, so later calls
+// retry. WithPanicRecovery overrides WithPanicPropagation.
+func WithPanicRecovery() Option {
+	return func(o *options) {
+		o.recoverPanics = true
+	}
+}
+
+// WithMaxAttempts limits the number of failed initializations to n. Once the
+// initialization function has failed n times, it is never called again and all
+// callers receive an error that wraps both ErrFrozen and the last failure.
+// Failures of a call whose context was done are not counted. A value of n less
+// than 1 means no limit.
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// WithBackoff spaces out retries after failed initializations. After the nth
+// consecutive failure, the initialization function is not called again until
+// base*2^(n-1) has elapsed, capped at limit. A limit of zero or less means no
+// cap other than the largest time.Duration. Each delay is reduced by a random
+// amount of up to jitter times the delay, where jitter is between 0 and 1.
+// Callers arriving during the backoff window receive the last error without
+// calling the initialization function.
+func WithBackoff(base, limit time.Duration, jitter float64) Option {
+	return func(o *options) {
+		o.backoff = backoff{base: base, limit: limit, jitter: min(max(jitter, 0), 1)}
+	}
+}
+
+// WithAttemptTimeout bounds each call of the initialization function to d. The
+// function receives a context derived from the caller's that is cancelled
+// after d; callers waiting for the attempt are still bound only by their own
+// contexts.
+func WithAttemptTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.attemptTimeout = d
+	}
+}
+
+// WithRetryBudget limits the total time spent retrying to d, measured from the
+// start of the first attempt. A failure after the budget is exhausted freezes
+// the Lazy as described for WithMaxAttempts. An attempt in progress when the
+// budget runs out is not interrupted; combine with WithAttemptTimeout to bound
+// it.
+func WithRetryBudget(d time.Duration) Option {
+	return func(o *options) {
+		o.retryBudget = d
+	}
+}
+
+// WithDetachedExecution runs the initialization function under a context that
+// carries the values of the caller's context but is not cancelled with it. If
+// the caller stops waiting, the initialization runs to completion in the
+// background so that its result benefits callers that are still waiting and
+// future callers. Combine with WithAttemptTimeout to bound the detached run.
+func WithDetachedExecution() Option {
+	return func(o *options) {
+		o.detached = true
+	}
+}
+
+// WithLeaderHandoff lets callers waiting for an initialization take it over
+// when the caller that started it stops waiting. As with
+// WithDetachedExecution, the initialization function runs under a context that
+// is not cancelled with the caller's, but here the context is cancelled, with
+// the last departing caller's cause, as soon as no caller is waiting for the
+// result anymore.
+func WithLeaderHandoff() Option {
+	return func(o *options) {
+		o.handoff = true
+	}
+}
+
+// WithMergedContext is like WithLeaderHandoff, but treats all interested
+// callers alike: the initialization function's context stays live as long as
+// at least one caller is waiting for the result, and once every caller has
+// gone away it is cancelled with a cause that wraps both ErrAbandoned and the
+// cause of the last caller to leave. This lets the function distinguish
+// abandonment from other cancellations.
+func WithMergedContext() Option {
+	return func(o *options) {
+		o.handoff = true
+		o.mergedContext = true
+	}
+}
+
+// WithSharedResults makes callers that wait for an initialization in progress
+// receive its outcome, like golang.org/x/sync/singleflight: if the
+// initialization fails, every caller that was waiting for it receives the same
+// error instead of retrying it in turn. Only later calls retry. An error
+// caused by the initializing caller's own context being done is not shared;
+// waiting callers retry instead.
+func WithSharedResults() Option {
+	return func(o *options) {
+		o.sharedResults = true
+	}
+}
+
+// WithMaxWait bounds how long a caller waits for another caller's
+// initialization to finish. A caller that waits longer than d gives up with
+// ErrWaitTimeout. The limit does not apply to the context passed to the
+// initialization function.
+func WithMaxWait(d time.Duration) Option {
+	return func(o *options) {
+		o.maxWait = d
+	}
+}
+
+// WithMaxWaiters limits the number of callers that may wait for an
+// initialization in progress to n. Callers beyond the limit fail immediately
+// with ErrBusy instead of blocking.
+func WithMaxWaiters(n int) Option {
+	return func(o *options) {
+		o.maxWaiters = n
+	}
+}
+
+// WithCheckOnCancel makes a caller whose context is done while waiting check
+// once more for a cached value before giving up. This closes the window in
+// which a caller reports cancellation although the value became available at
+// nearly the same moment.
+func WithCheckOnCancel() Option {
+	return func(o *options) {
+		o.checkOnCancel = true
+	}
+}
+
+// WithMinDeadline refuses to start an initialization when the caller's
+// context has less than d remaining before its deadline, returning
+// context.DeadlineExceeded immediately instead of starting work that cannot
+// finish in time. Refusals do not count as failed attempts. The guard does not
+// apply with WithDetachedExecution or WithLeaderHandoff, since the
+// initialization then outlives the caller's deadline.
+func WithMinDeadline(d time.Duration) Option {
+	return func(o *options) {
+		o.minDeadline = d
+	}
+}
+
+// WithHedging launches additional parallel calls of the initialization
+// function when a call is slow: each time delay elapses without a result,
+// another call starts, up to n calls in total. The first successful result is
+// used and the other calls' contexts are cancelled. If every call fails, the
+// initialization fails with the error of the last call to fail and counts as a
+// single failed attempt for retry purposes.
+func WithHedging(delay time.Duration, n int) Option {
+	return func(o *options) {
+		o.hedgeDelay = delay
+		o.hedgeAttempts = n
+	}
+}
+
+// WithPermanentErrors classifies initialization errors: an error for which
+// permanent returns true is never retried and freezes the Lazy as described for
+// WithMaxAttempts. Other errors keep the default retry behavior.
+func WithPermanentErrors(permanent func(error) bool) Option {
+	return func(o *options) {
+		o.permanent = permanent
+	}
+}
+
+// PermanentErrors returns a classifier for WithPermanentErrors that reports
+// whether an error matches any of targets according to errors.Is.
+func PermanentErrors(targets ...error) func(error) bool {
+	return func(err error) bool {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithCapacity limits a Map to n entries, evicting the least recently used
+// entries when a new key would exceed the limit. Entries whose load is in
+// progress are not evicted, so the limit may be exceeded briefly while many
+// loads run at once. With WithShards, each shard holds an equal share of the
+// capacity and recency is tracked per shard.
+func WithCapacity(n int) Option {
+	return func(o *options) {
+		o.capacity = n
+	}
+}
+
+// WithTTL makes the values of a Map expire d after they were loaded. The next
+// Get of an expired key reloads it, and concurrent callers wait for that
+// single reload. A loader can override the duration for its value with SetTTL.
+func WithTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.ttl = d
+	}
+}
+
+// WithStaleWhileRevalidate lets a Map serve an expired value for up to d after
+// its expiry while a single background load refreshes it, instead of making
+// readers wait for the reload. Once d has passed, readers wait as usual. The
+// background load runs under the triggering caller's context without its
+// cancellation. It has no effect without WithTTL or SetTTL.
+func WithStaleWhileRevalidate(d time.Duration) Option {
+	return func(o *options) {
+		o.staleWhileRevalidate = d
+	}
+}
+
+// WithEarlyExpiration makes a Map refresh values with a TTL in the background
+// shortly before they expire, using the XFetch algorithm: each Get refreshes
+// its value early with a probability that grows as the expiry approaches and
+// with the time the value took to load, scaled by beta. This spreads the
+// refresh of a hot key over the readers before its expiry instead of having
+// them all wait when it expires. Callers keep receiving the current value
+// during the refresh. A beta of 1 is a good default; larger values refresh
+// earlier.
+func WithEarlyExpiration(beta float64) Option {
+	return func(o *options) {
+		o.earlyExpiration = beta
+	}
+}
+
+// WithRefreshAhead makes a Map refresh the values of hot keys in the background
+// window before they expire, so that their readers never wait for a reload. A
+// key becomes hot once its current value has been read hits times. At most
+// concurrency refreshes run at once; a refresh that would exceed the limit is
+// skipped, and the key is then reloaded as usual when it expires. A
+// concurrency of zero or less means no limit. Refreshes run under
+// context.Background. It only applies to values with a TTL.
+func WithRefreshAhead(window time.Duration, hits, concurrency int) Option {
+	return func(o *options) {
+		o.refreshWindow = window
+		o.refreshHits = max(hits, 1)
+		o.refreshConcurrency = concurrency
+	}
+}
+
+// WithJanitor makes a Map remove expired values every interval in a background
+// goroutine, rather than only when their key is next read, so that keys that
+// are never read again do not hold on to memory and are reported to the
+// OnEvict callback of MapOptions. Values still served by WithStaleWhileRevalidate and
+// keys being loaded are left alone. The goroutine runs until the Map's Close
+// method is called.
+func WithJanitor(interval time.Duration) Option {
+	return func(o *options) {
+		o.janitor = interval
+	}
+}
+
+// WithCodec sets the Codec used by the Snapshot and Restore methods of a Map.
+// The default is GobCodec.
+func WithCodec(c Codec) Option {
+	return func(o *options) {
+		o.codec = c
+	}
+}
+
+// WithShards splits the entries of a Map into n shards, each with its own
+// mutex, so that goroutines accessing unrelated keys rarely contend. The
+// default is a single shard.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = n
+	}
+}
+
+// WithCollectCleanup registers a cleanup with runtime.AddCleanup so that the
+// value of a Lazy that becomes unreachable without being closed is released as
+// by Close, as a safety net for owners that are simply dropped. The cleanup
+// function and the value must not reference the Lazy, or it is never
+// collected.
+func WithCollectCleanup() Option {
+	return func(o *options) {
+		o.collectCleanup = true
+	}
+}
+
+// WithName names a Lazy, or the values of a Map, for diagnostics. Errors of
+// failed initializations are wrapped in an Error with the name, so that they
+// identify their source. The initializations of a named Lazy run with the
+// profiler labels "lazy", set to name, and "lazy.attempt", set to the attempt
+// number, so that CPU and goroutine profiles attribute the time spent in them.
+// Execution traces name the tasks of initializations and the regions of waits
+// for them after it.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithCallSite records where it is called, typically in the construction of a
+// Lazy or Map, and wraps the errors of failed initializations in an Error
+// reporting that call site, as with WithName.
+func WithCallSite() Option {
+	_, file, line, ok := runtime.Caller(1)
+	return func(o *options) {
+		if ok {
+			o.site = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+		}
+	}
+}
@@ -0,0 +1,466 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestWithPanicPropagation(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			close(started)
+			<-proceed
+			panic("boom")
+		}, WithPanicPropagation())
+
+		get := func() (r any) {
+			defer func() { r = recover() }()
+			l.Get(context.Background())
+			return nil
+		}
+
+		results := make(chan any, 2)
+		go func() { results <- get() }()
+		<-started
+
+		// A second caller waits behind the panicking initialization.
+		go func() { results <- get() }()
+		synctest.Wait()
+		close(proceed)
+
+		for range 2 {
+			if r := <-results; r != "boom" {
+				t.Errorf("got panic %v, want %q", r, "boom")
+			}
+		}
+
+		// Future callers panic with the same value.
+		if r := get(); r != "boom" {
+			t.Errorf("got panic %v, want %q", r, "boom")
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("function called %d times, want 1", got)
+		}
+	})
+}
+
+func TestPanicWithoutPropagationRetries(t *testing.T) {
+	var calls atomic.Int32
+
+	l := New(func(ctx context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		return 1, nil
+	})
+
+	func() {
+		defer func() { recover() }()
+		l.Get(context.Background())
+	}()
+
+	result, err := l.Get(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 1 {
+		t.Fatalf("got %d, want 1", result)
+	}
+}
+
+func TestWithPanicRecovery(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		errCause := errors.New("cause")
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			if calls.Add(1) == 1 {
+				close(started)
+				<-proceed
+				panic(errCause)
+			}
+			return 1, nil
+		}, WithPanicRecovery())
+
+		errs := make(chan error, 2)
+		go func() {
+			_, err := l.Get(context.Background())
+			errs <- err
+		}()
+		<-started
+
+		// A second caller waits behind the panicking initialization and
+		// receives the same error instead of retrying.
+		go func() {
+			_, err := l.Get(context.Background())
+			errs <- err
+		}()
+		synctest.Wait()
+		close(proceed)
+
+		for range 2 {
+			err := <-errs
+			var perr *PanicError
+			if !errors.As(err, &perr) {
+				t.Fatalf("got error %v, want *PanicError", err)
+			}
+			if !errors.Is(err, errCause) {
+				t.Errorf("got error %v, want wrapped %v", err, errCause)
+			}
+			if len(perr.Stack) == 0 {
+				t.Error("expected stack trace")
+			}
+		}
+
+		// The lazy remains retryable.
+		result, err := l.Get(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != 1 {
+			t.Fatalf("got %d, want 1", result)
+		}
+		if got := calls.Load(); got != 2 {
+			t.Fatalf("function called %d times, want 2", got)
+		}
+	})
+}
+
+func TestWithAttemptTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+
+		l := New(func(ctx context.Context) (int, error) {
+			if calls.Add(1) == 1 {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			return 1, nil
+		}, WithAttemptTimeout(time.Second))
+
+		start := time.Now()
+		if _, err := l.Get(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+		if elapsed := time.Since(start); elapsed != time.Second {
+			t.Fatalf("attempt took %v, want 1s", elapsed)
+		}
+
+		result, err := l.Get(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != 1 {
+			t.Fatalf("got %d, want 1", result)
+		}
+	})
+}
+
+func TestWithDetachedExecution(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			close(started)
+			select {
+			case <-proceed:
+				return 1, nil
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			}
+		}, WithDetachedExecution())
+
+		// The first caller gives up while the initialization is running.
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() {
+			_, err := l.Get(ctx)
+			errs <- err
+		}()
+		<-started
+
+		results := make(chan int, 1)
+		go func() {
+			v, _ := l.Get(context.Background())
+			results <- v
+		}()
+		synctest.Wait()
+
+		cancel()
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Fatalf("got error %v, want %v", err, context.Canceled)
+		}
+
+		// The initialization keeps running for the remaining caller.
+		close(proceed)
+		if v := <-results; v != 1 {
+			t.Fatalf("got %d, want 1", v)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("function called %d times, want 1", got)
+		}
+	})
+}
+
+func TestWithDetachedExecution_Panic(t *testing.T) {
+	l := New(func(ctx context.Context) (int, error) {
+		panic("boom")
+	}, WithDetachedExecution())
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("got panic %v, want %q", r, "boom")
+		}
+	}()
+	l.Get(context.Background())
+}
+
+func TestWithCheckOnCancel(t *testing.T) {
+	for _, check := range []bool{false, true} {
+		synctest.Test(t, func(t *testing.T) {
+			started := make(chan struct{})
+			proceed := make(chan struct{})
+
+			var opts []Option
+			if check {
+				opts = append(opts, WithCheckOnCancel())
+			}
+			l := New(func(ctx context.Context) (int, error) {
+				close(started)
+				<-proceed
+				return 1, nil
+			}, opts...)
+
+			go l.Get(context.Background())
+			<-started
+
+			ctx, cancel := context.WithCancel(context.Background())
+			type result struct {
+				val int
+				err error
+			}
+			results := make(chan result, 1)
+			go func() {
+				v, err := l.Get(ctx)
+				results <- result{v, err}
+			}()
+			synctest.Wait()
+
+			// The value becomes available while the semaphore is still held,
+			// at the same moment the waiting caller gives up.
+			l.Set(2)
+			cancel()
+
+			r := <-results
+			switch {
+			case check && (r.err != nil || r.val != 2):
+				t.Errorf("got (%d, %v), want (2, nil)", r.val, r.err)
+			case !check && !errors.Is(r.err, context.Canceled):
+				t.Errorf("got error %v, want %v", r.err, context.Canceled)
+			}
+			close(proceed)
+		})
+	}
+}
+
+func TestWithMaxWait(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			close(started)
+			time.Sleep(time.Minute)
+			return 1, ctx.Err()
+		}, WithMaxWait(50*time.Millisecond))
+
+		results := make(chan error, 1)
+		go func() {
+			_, err := l.Get(context.Background())
+			results <- err
+		}()
+		<-started
+
+		// A waiting caller gives up quickly.
+		start := time.Now()
+		if _, err := l.Get(context.Background()); !errors.Is(err, ErrWaitTimeout) {
+			t.Fatalf("got error %v, want %v", err, ErrWaitTimeout)
+		}
+		if elapsed := time.Since(start); elapsed != 50*time.Millisecond {
+			t.Fatalf("waited %v, want 50ms", elapsed)
+		}
+
+		// The initialization itself is not bound by the wait limit.
+		if err := <-results; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestWithMaxWaiters(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			close(started)
+			<-proceed
+			return 1, nil
+		}, WithMaxWaiters(1))
+
+		go l.Get(context.Background())
+		<-started
+
+		results := make(chan int, 1)
+		go func() {
+			v, _ := l.Get(context.Background())
+			results <- v
+		}()
+		synctest.Wait()
+
+		// The limit is reached, so further callers fail fast.
+		if _, err := l.Get(context.Background()); !errors.Is(err, ErrBusy) {
+			t.Fatalf("got error %v, want %v", err, ErrBusy)
+		}
+
+		close(proceed)
+		if v := <-results; v != 1 {
+			t.Fatalf("got %d, want 1", v)
+		}
+	})
+}
+
+func TestWithMinDeadline(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+
+		l := New(func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			return 1, nil
+		}, WithMinDeadline(time.Second), WithMaxAttempts(1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		if _, err := l.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+		if got := calls.Load(); got != 0 {
+			t.Fatalf("function called %d times, want 0", got)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if _, err := l.Get(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestWithMinDeadline_Detached(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		l := New(func(ctx context.Context) (int, error) {
+			return 1, nil
+		}, WithMinDeadline(time.Second), WithDetachedExecution())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		if _, err := l.Get(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestWithSharedResults(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		errOutage := errors.New("outage")
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			if calls.Add(1) == 1 {
+				close(started)
+				<-proceed
+				return 0, errOutage
+			}
+			return 1, nil
+		}, WithSharedResults())
+
+		errs := make(chan error, 3)
+		go func() {
+			_, err := l.Get(context.Background())
+			errs <- err
+		}()
+		<-started
+
+		for range 2 {
+			go func() {
+				_, err := l.Get(context.Background())
+				errs <- err
+			}()
+		}
+		synctest.Wait()
+		close(proceed)
+
+		// All callers of the failed initialization see its error.
+		for range 3 {
+			if err := <-errs; !errors.Is(err, errOutage) {
+				t.Fatalf("got error %v, want %v", err, errOutage)
+			}
+		}
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("function called %d times, want 1", got)
+		}
+
+		// Later calls retry.
+		if result, err := l.Get(context.Background()); err != nil || result != 1 {
+			t.Fatalf("got (%d, %v), want (1, nil)", result, err)
+		}
+	})
+}
+
+func TestWithSharedResults_LeaderCancellationNotShared(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		started := make(chan struct{})
+
+		l := New(func(ctx context.Context) (int, error) {
+			select {
+			case <-started:
+				return 1, nil
+			default:
+			}
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}, WithSharedResults())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go l.Get(ctx)
+		<-started
+
+		results := make(chan int, 1)
+		go func() {
+			v, _ := l.Get(context.Background())
+			results <- v
+		}()
+		synctest.Wait()
+
+		cancel()
+		if v := <-results; v != 1 {
+			t.Fatalf("got %d, want 1", v)
+		}
+	})
+}
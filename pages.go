@@ -0,0 +1,94 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"sync/atomic"
+)
+
+// Pages lazily fetches and caches the pages of a large dataset, such as the
+// results of a paginated API, so that it can be read on demand without being
+// loaded upfront. The pages are numbered from zero and cached in a Map, with
+// its semantics. Pages is safe for concurrent use by multiple goroutines.
+type Pages[T any] struct {
+	m        *Map[int, T]
+	prefetch int
+
+	// end is one more than the first page for which fetch returned io.EOF,
+	// or zero if none has, so that pages past the end are not prefetched.
+	end atomic.Int64
+}
+
+// NewPages returns Pages whose page n is fetched by calling fetch with n.
+// fetch returns io.EOF for the pages past the end of the dataset. Once a page
+// has been read, the prefetch pages following it are fetched in the background
+// if they are not cached yet, so that a sequential reader rarely waits. The
+// options apply to the underlying Map, for instance to bound the number of
+// pages kept with WithCapacity. Pages past the first one for which fetch
+// returned io.EOF are not prefetched.
+func NewPages[T any](fetch func(ctx context.Context, n int) (T, error), prefetch int, opts ...Option) *Pages[T] {
+	p := &Pages[T]{prefetch: prefetch}
+	p.m = NewMap(func(ctx context.Context, n int) (T, error) {
+		v, err := fetch(ctx, n)
+		if errors.Is(err, io.EOF) {
+			p.setEnd(n)
+		}
+		return v, err
+	}, opts...)
+	return p
+}
+
+// setEnd records that page n is past the end of the dataset.
+func (p *Pages[T]) setEnd(n int) {
+	for {
+		end := p.end.Load()
+		if end != 0 && end <= int64(n)+1 || p.end.CompareAndSwap(end, int64(n)+1) {
+			return
+		}
+	}
+}
+
+// past reports whether page n is known to be past the end of the dataset.
+func (p *Pages[T]) past(n int) bool {
+	end := p.end.Load()
+	return end != 0 && int64(n) >= end-1
+}
+
+// GetPage returns page n, fetching it if it is not cached. Concurrent calls for
+// the same page wait for a single fetch, as with Map.Get.
+func (p *Pages[T]) GetPage(ctx context.Context, n int) (T, error) {
+	v, err := p.m.Get(ctx, n)
+	if err != nil {
+		return v, err
+	}
+	for i := n + 1; i <= n+p.prefetch && !p.past(i); i++ {
+		if !p.m.ContainsKey(i) {
+			go p.m.Get(context.WithoutCancel(ctx), i)
+		}
+	}
+	return v, nil
+}
+
+// All returns a sequence of the pages from the first, with a nil error, that
+// ends after the last one. If a page cannot be fetched, the sequence yields
+// the error and ends.
+func (p *Pages[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for n := 0; ; n++ {
+			v, err := p.GetPage(ctx, n)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Forget discards page n, if it is cached, so that it is fetched again.
+func (p *Pages[T]) Forget(n int) {
+	p.m.Forget(n)
+}
@@ -0,0 +1,96 @@
+package lazy
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"testing/synctest"
+)
+
+func TestPages(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mu sync.Mutex
+		var fetched []int
+		p := NewPages(func(ctx context.Context, n int) ([]int, error) {
+			mu.Lock()
+			fetched = append(fetched, n)
+			mu.Unlock()
+			if n >= 3 {
+				return nil, io.EOF
+			}
+			return []int{2 * n, 2*n + 1}, nil
+		}, 1)
+
+		if v, err := p.GetPage(context.Background(), 1); err != nil || !slices.Equal(v, []int{2, 3}) {
+			t.Fatalf("got %v, %v, want [2 3], nil", v, err)
+		}
+		synctest.Wait()
+		slices.Sort(fetched)
+		if !slices.Equal(fetched, []int{1, 2}) {
+			t.Fatalf("fetched %v, want [1 2] with the next page prefetched", fetched)
+		}
+
+		var got []int
+		for page, err := range p.All(context.Background()) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, page...)
+		}
+		if !slices.Equal(got, []int{0, 1, 2, 3, 4, 5}) {
+			t.Fatalf("got %v, want [0 1 2 3 4 5]", got)
+		}
+		synctest.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		for n := range 3 {
+			if c := countOf(fetched, n); c != 1 {
+				t.Fatalf("fetched page %d %d times, want once", n, c)
+			}
+		}
+	})
+}
+
+func countOf(s []int, v int) int {
+	n := 0
+	for _, x := range s {
+		if x == v {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPages_NoPrefetchPastEnd(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mu sync.Mutex
+		fetched := make(map[int]int)
+		p := NewPages(func(ctx context.Context, n int) (int, error) {
+			mu.Lock()
+			fetched[n]++
+			mu.Unlock()
+			if n >= 2 {
+				return 0, io.EOF
+			}
+			return n, nil
+		}, 3)
+
+		read := func() {
+			for _, err := range p.All(context.Background()) {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			synctest.Wait()
+		}
+		read()
+		before := fetched[3] + fetched[4]
+		read()
+		read()
+		if got := fetched[3] + fetched[4]; got != before {
+			t.Fatalf("fetched pages past the end %d more times once the end was known, want 0", got-before)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// A Pipeline is a computation in stages, such as load, parse and index, where
+// each stage is a Lazy computed from the value of the previous one. The stages
+// are members of a Group, named after them, so that they can be primed,
+// checked and drawn together, and invalidating a stage with Invalidate also
+// invalidates the stages computed from it. The zero value is an empty Pipeline
+// ready to use. A Pipeline is safe for concurrent use by multiple goroutines.
+type Pipeline struct {
+	g Group
+
+	mu         sync.Mutex
+	names      map[Handle]string
+	stages     map[string]Handle
+	dependents map[string][]string
+}
+
+// Source adds to p a first stage called name, computed by f, and returns it. The
+// stage is created by New with opts, after WithName(name). Source panics if p
+// already has a stage called name.
+func Source[T any](p *Pipeline, name string, f func(context.Context) (T, error), opts ...Option) *Lazy[T] {
+	l := New(f, append([]Option{WithName(name)}, opts...)...)
+	p.add(name, l, nil)
+	return l
+}
+
+// Stage adds to p a stage called name, computed by f from the value of in, a
+// stage of p, and returns it. The stage is created by AndThen with opts, after
+// WithName(name). Stage panics if p already has a stage called name or if in is
+// not a stage of p.
+func Stage[A, B any](p *Pipeline, name string, in *Lazy[A], f func(context.Context, A) (B, error), opts ...Option) *Lazy[B] {
+	l := AndThen(in, f, append([]Option{WithName(name)}, opts...)...)
+	p.add(name, l, in)
+	return l
+}
+
+// add adds the stage h called name, computed from the stage in, if not nil.
+func (p *Pipeline) add(name string, h, in Handle) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.stages[name]; ok {
+		panic(fmt.Sprintf("lazy: pipeline stage %q added twice", name))
+	}
+	var deps []string
+	if in != nil {
+		dep, ok := p.names[in]
+		if !ok {
+			panic(fmt.Sprintf("lazy: input of pipeline stage %q is not a stage of the pipeline", name))
+		}
+		deps = []string{dep}
+	}
+	if p.stages == nil {
+		p.names = make(map[Handle]string)
+		p.stages = make(map[string]Handle)
+		p.dependents = make(map[string][]string)
+	}
+	p.names[h] = name
+	p.stages[name] = h
+	for _, dep := range deps {
+		p.dependents[dep] = append(p.dependents[dep], name)
+	}
+	p.g.Add(name, h, deps...)
+}
+
+// Invalidate invalidates the stage of p called name and, transitively, the
+// stages computed from it, so that the next Get of any of them recomputes it
+// from the current values of the earlier stages. It does nothing if p has no
+// stage called name.
+func (p *Pipeline) Invalidate(name string) {
+	p.mu.Lock()
+	var stages []Handle
+	queue := []string{name}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if h, ok := p.stages[name]; ok {
+			stages = append(stages, h)
+			queue = append(queue, p.dependents[name]...)
+		}
+	}
+	p.mu.Unlock()
+	for _, h := range stages {
+		h.Invalidate()
+	}
+}
+
+// Group returns the Group of the stages of p, to prime, check, report on or
+// draw them. Invalidate stages with the Invalidate method of p, to invalidate
+// the later stages with them.
+func (p *Pipeline) Group() *Group {
+	return &p.g
+}
@@ -0,0 +1,74 @@
+package lazy
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	var p Pipeline
+	data := "1,2,3"
+	loads := 0
+	load := Source(&p, "load", func(ctx context.Context) (string, error) {
+		loads++
+		return data, nil
+	})
+	parse := Stage(&p, "parse", load, func(ctx context.Context, s string) ([]int, error) {
+		var ns []int
+		for f := range strings.SplitSeq(s, ",") {
+			n, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, err
+			}
+			ns = append(ns, n)
+		}
+		return ns, nil
+	})
+	sum := Stage(&p, "sum", parse, func(ctx context.Context, ns []int) (int, error) {
+		total := 0
+		for _, n := range ns {
+			total += n
+		}
+		return total, nil
+	})
+
+	if err := p.Group().Prime(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := sum.Get(context.Background()); v != 6 {
+		t.Fatalf("got sum %d, want 6", v)
+	}
+
+	data = "1,2,3,4"
+	p.Invalidate("load")
+	for _, h := range []Handle{load, parse, sum} {
+		if h.State() != StateIdle {
+			t.Fatalf("got state %v after invalidating the source, want %v", h.State(), StateIdle)
+		}
+	}
+	if v, _ := sum.Get(context.Background()); v != 10 {
+		t.Fatalf("got sum %d after invalidation, want 10", v)
+	}
+	if loads != 2 {
+		t.Fatalf("got %d loads, want 2", loads)
+	}
+
+	data = "x"
+	p.Invalidate("parse")
+	if v, _ := sum.Get(context.Background()); v != 10 || loads != 2 {
+		t.Fatalf("got sum %d after %d loads, want the earlier stage kept", v, loads)
+	}
+}
+
+func TestPipeline_UnknownInput(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Stage did not panic")
+		}
+	}()
+	var p Pipeline
+	in := New(func(ctx context.Context) (int, error) { return 0, nil })
+	Stage(&p, "out", in, func(ctx context.Context, v int) (int, error) { return v, nil })
+}
@@ -0,0 +1,119 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// A Pool hands out up to a fixed number of instances of a resource, such as
+// connections, constructing them on demand: an instance is only constructed
+// when one is acquired while all the constructed ones are in use. It is like a
+// Lazy generalized from one instance to several. A Pool is safe for concurrent
+// use by multiple goroutines.
+type Pool[T any] struct {
+	factory func(context.Context) (T, error)
+
+	// slots holds a token for each instance in use or being constructed.
+	slots chan struct{}
+
+	// closed is closed by Close, to wake up the callers waiting for a slot.
+	closed chan struct{}
+
+	mu   sync.Mutex
+	idle []T
+}
+
+// NewPool returns a Pool of at most n instances constructed by factory. NewPool
+// panics if n is less than one.
+func NewPool[T any](n int, factory func(context.Context) (T, error)) *Pool[T] {
+	if n < 1 {
+		panic("lazy: NewPool called with a size less than one")
+	}
+	return &Pool[T]{factory: factory, slots: make(chan struct{}, n), closed: make(chan struct{})}
+}
+
+// Acquire returns an idle instance of p, constructing one with ctx if there is
+// none and fewer instances than the size of p exist, or else waiting for one to
+// be released. It returns the error of the factory, if it fails, context.Cause
+// of ctx if ctx is done first, and ErrClosed once p is closed. The instance
+// must be handed back with Release.
+func (p *Pool[T]) Acquire(ctx context.Context) (T, error) {
+	var zero T
+	if p.isClosed() {
+		return zero, ErrClosed
+	}
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.closed:
+		return zero, ErrClosed
+	case <-ctx.Done():
+		return zero, context.Cause(ctx)
+	}
+
+	p.mu.Lock()
+	if p.isClosed() {
+		p.mu.Unlock()
+		<-p.slots
+		return zero, ErrClosed
+	}
+	if n := len(p.idle); n > 0 {
+		v := p.idle[n-1]
+		p.idle[n-1] = zero
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return v, nil
+	}
+	p.mu.Unlock()
+
+	v, err := p.factory(ctx)
+	if err != nil {
+		<-p.slots
+		return zero, err
+	}
+	return v, nil
+}
+
+// Release hands v, acquired from p, back to p for reuse. If p has been closed,
+// Release closes v instead, as Close does.
+func (p *Pool[T]) Release(v T) {
+	p.mu.Lock()
+	closed := p.isClosed()
+	if !closed {
+		p.idle = append(p.idle, v)
+	}
+	p.mu.Unlock()
+	<-p.slots
+	if closed {
+		closeValue(context.Background(), v)
+	}
+}
+
+// Close closes p for good: calls of Acquire waiting for an instance and later
+// ones return ErrClosed, and the instances in use are closed when released.
+// Close closes the idle instances that implement ContextCloser or io.Closer and
+// returns their errors joined with errors.Join. Closing p again does nothing.
+func (p *Pool[T]) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.isClosed() {
+		close(p.closed)
+	}
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+	var errs []error
+	for _, v := range idle {
+		errs = append(errs, closeValue(ctx, v))
+	}
+	return errors.Join(errs...)
+}
+
+// isClosed reports whether p has been closed.
+func (p *Pool[T]) isClosed() bool {
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,98 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mu sync.Mutex
+		var closed []string
+		created := 0
+		p := NewPool(2, func(ctx context.Context) (*closer, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			created++
+			return &closer{name: string(rune('a' + created - 1)), closed: &closed}, nil
+		})
+
+		a, _ := p.Acquire(context.Background())
+		p.Release(a)
+		a, _ = p.Acquire(context.Background())
+		if created != 1 {
+			t.Fatalf("created %d instances for sequential use, want 1", created)
+		}
+		b, _ := p.Acquire(context.Background())
+
+		got := make(chan *closer)
+		go func() {
+			c, _ := p.Acquire(context.Background())
+			got <- c
+		}()
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if created != 2 {
+			t.Fatalf("created %d instances, want the pool size 2", created)
+		}
+		p.Release(b)
+		if c := <-got; c != b {
+			t.Fatalf("got instance %s, want the released %s", c.name, b.name)
+		}
+
+		if err := p.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Acquire(context.Background()); !errors.Is(err, ErrClosed) {
+			t.Fatalf("got error %v after Close, want %v", err, ErrClosed)
+		}
+		p.Release(a)
+		p.Release(b)
+		slices.Sort(closed)
+		if !slices.Equal(closed, []string{"a", "b"}) {
+			t.Fatalf("got closed %v, want [a b]", closed)
+		}
+	})
+}
+
+func TestPool_FactoryError(t *testing.T) {
+	errFail := errors.New("fail")
+	fail := true
+	p := NewPool(1, func(ctx context.Context) (int, error) {
+		if fail {
+			return 0, errFail
+		}
+		return 1, nil
+	})
+	if _, err := p.Acquire(context.Background()); !errors.Is(err, errFail) {
+		t.Fatalf("got error %v, want %v", err, errFail)
+	}
+	fail = false
+	if v, err := p.Acquire(context.Background()); err != nil || v != 1 {
+		t.Fatalf("got %v, %v, want the failed slot to be reusable", v, err)
+	}
+}
+
+func TestPool_CloseWaiting(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		p := NewPool(1, func(ctx context.Context) (int, error) { return 1, nil })
+		v, _ := p.Acquire(context.Background())
+
+		errc := make(chan error)
+		go func() {
+			_, err := p.Acquire(context.Background())
+			errc <- err
+		}()
+		synctest.Wait()
+		p.Close(context.Background())
+		if err := <-errc; !errors.Is(err, ErrClosed) {
+			t.Fatalf("got error %v for a waiter during Close, want %v", err, ErrClosed)
+		}
+		p.Release(v)
+	})
+}
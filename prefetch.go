@@ -0,0 +1,170 @@
+package lazy
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// A Primer is a value that can be initialized ahead of its use, such as a
+// Lazy, a Group or a Future.
+type Primer interface {
+	Prime(ctx context.Context) error
+}
+
+// PrimeFunc returns a function that primes p with ctx, for use with the Go
+// method of an errgroup.Group or a similar startup routine:
+//
+//	g, ctx := errgroup.WithContext(ctx)
+//	g.Go(lazy.PrimeFunc(ctx, db))
+//	g.Go(lazy.PrimeFunc(ctx, templates))
+//	if err := g.Wait(); err != nil {
+//		return err
+//	}
+func PrimeFunc(ctx context.Context, p Primer) func() error {
+	return func() error {
+		return p.Prime(ctx)
+	}
+}
+
+// prefetchPause is how long a Prefetcher waits before checking again whether
+// the process is still under load.
+const prefetchPause = 100 * time.Millisecond
+
+// A Prefetcher warms primers in the background, replacing ad hoc goroutines
+// with bounded concurrency: primers are primed in the order of their priority,
+// at most a given number at once, and not while the process is under load.
+// A Prefetcher is safe for concurrent use by multiple goroutines.
+type Prefetcher struct {
+	ctx      context.Context
+	limit    int
+	overload func() bool
+
+	mu      sync.Mutex
+	queue   prefetchQueue
+	seq     uint64 // of the last prefetch, to keep the order of equal ones
+	running int
+	wake    chan struct{}
+}
+
+// NewPrefetcher returns a Prefetcher that primes at most limit primers at once
+// with ctx, or any number if limit is zero or less. If overload is not nil, it
+// is called before priming each primer, and priming is paused while it reports
+// that the process is under load, for instance from its CPU usage or the
+// latency of its requests. The Prefetcher stops once ctx is done, dropping the
+// primers not yet started.
+func NewPrefetcher(ctx context.Context, limit int, overload func() bool) *Prefetcher {
+	p := &Prefetcher{ctx: ctx, limit: limit, overload: overload, wake: make(chan struct{}, 1)}
+	go p.run()
+	return p
+}
+
+// Prefetch schedules the priming of pr. Primers of higher priority are primed
+// first, then those of lower estimated cost, such as the expected duration of
+// their initialization, then those scheduled first. The error of Prime is
+// ignored: a primer that fails is initialized again when it is used. Priming a
+// primer that is already initialized is cheap, so pr may be scheduled
+// regardless of its state.
+func (p *Prefetcher) Prefetch(pr Primer, priority int, cost time.Duration) {
+	p.mu.Lock()
+	p.seq++
+	heap.Push(&p.queue, prefetch{pr, priority, cost, p.seq})
+	p.mu.Unlock()
+	p.signal()
+}
+
+// Pending returns the number of primers scheduled but not started yet.
+func (p *Prefetcher) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queue.Len()
+}
+
+// signal wakes up the loop of p.
+func (p *Prefetcher) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run starts the scheduled primers until the context of p is done.
+func (p *Prefetcher) run() {
+	for {
+		pr, ok := p.next()
+		if !ok {
+			select {
+			case <-p.wake:
+				continue
+			case <-p.ctx.Done():
+				return
+			}
+		}
+		if p.overload != nil && p.overload() {
+			p.mu.Lock()
+			heap.Push(&p.queue, pr)
+			p.mu.Unlock()
+			select {
+			case <-time.After(prefetchPause):
+				continue
+			case <-p.ctx.Done():
+				return
+			}
+		}
+		p.mu.Lock()
+		p.running++
+		p.mu.Unlock()
+		go func() {
+			pr.Prime(p.ctx)
+			p.mu.Lock()
+			p.running--
+			p.mu.Unlock()
+			p.signal()
+		}()
+	}
+}
+
+// next pops the next primer to start, if any, within the limit of p.
+func (p *Prefetcher) next() (prefetch, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.queue.Len() == 0 || p.limit > 0 && p.running >= p.limit || p.ctx.Err() != nil {
+		return prefetch{}, false
+	}
+	return heap.Pop(&p.queue).(prefetch), true
+}
+
+// A prefetch is a primer scheduled by Prefetch.
+type prefetch struct {
+	Primer
+	priority int
+	cost     time.Duration
+	seq      uint64
+}
+
+// prefetchQueue is a heap of prefetches, the next to start first.
+type prefetchQueue []prefetch
+
+func (q prefetchQueue) Len() int { return len(q) }
+
+func (q prefetchQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	return cmp.Or(
+		cmp.Compare(b.priority, a.priority),
+		cmp.Compare(a.cost, b.cost),
+		cmp.Compare(a.seq, b.seq),
+	) < 0
+}
+
+func (q prefetchQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *prefetchQueue) Push(x any) { *q = append(*q, x.(prefetch)) }
+
+func (q *prefetchQueue) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,75 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestPrefetcher(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mu sync.Mutex
+		var order []string
+		newLazy := func(name string) *Lazy[string] {
+			return New(func(ctx context.Context) (string, error) {
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				time.Sleep(time.Second)
+				return name, nil
+			})
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var overloaded atomic.Bool
+		overloaded.Store(true)
+		p := NewPrefetcher(ctx, 1, overloaded.Load)
+		p.Prefetch(newLazy("low"), 0, 0)
+		p.Prefetch(newLazy("expensive"), 1, time.Minute)
+		p.Prefetch(newLazy("cheap"), 1, time.Second)
+		f := NewFuture(func(ctx context.Context) (string, error) {
+			mu.Lock()
+			order = append(order, "future")
+			mu.Unlock()
+			return "future", nil
+		})
+		p.Prefetch(f, 2, 0)
+
+		time.Sleep(time.Minute)
+		if len(order) != 0 || p.Pending() != 4 {
+			t.Fatalf("primed %v under load, want nothing", order)
+		}
+
+		overloaded.Store(false)
+		time.Sleep(time.Minute)
+		synctest.Wait()
+		if want := []string{"future", "cheap", "expensive", "low"}; !slices.Equal(order, want) {
+			t.Fatalf("primed %v, want %v", order, want)
+		}
+		if v, err := f.Await(context.Background()); err != nil || v != "future" {
+			t.Fatalf("got %q, %v from the Future, want future, nil", v, err)
+		}
+	})
+}
+
+func TestPrimeFunc(t *testing.T) {
+	errFail := errors.New("fail")
+	ok := New(func(ctx context.Context) (int, error) { return 1, nil })
+	failing := New(func(ctx context.Context) (int, error) { return 0, errFail })
+
+	if err := PrimeFunc(context.Background(), ok)(); err != nil {
+		t.Fatal(err)
+	}
+	if ok.State() != StateReady {
+		t.Fatalf("got state %v, want %v", ok.State(), StateReady)
+	}
+	if err := PrimeFunc(context.Background(), failing)(); !errors.Is(err, errFail) {
+		t.Fatalf("got error %v, want %v", err, errFail)
+	}
+}
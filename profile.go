@@ -0,0 +1,20 @@
+package lazy
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+)
+
+// labeledCall is like call, but runs f with the profiler labels of l if it has
+// a name. It must be called with sem held.
+func (l *Lazy[T]) labeledCall(ctx context.Context, attempt int, f func(context.Context) (T, func(context.Context) error, error)) (value T, cleanup func(context.Context) error, err error) {
+	if l.opts.name == "" {
+		return l.call(ctx, f)
+	}
+	labels := pprof.Labels("lazy", l.opts.name, "lazy.attempt", strconv.Itoa(attempt))
+	pprof.Do(ctx, labels, func(ctx context.Context) {
+		value, cleanup, err = l.call(ctx, f)
+	})
+	return value, cleanup, err
+}
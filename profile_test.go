@@ -0,0 +1,26 @@
+package lazy
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+)
+
+func TestWithName_ProfilerLabels(t *testing.T) {
+	l := New(func(ctx context.Context) (map[string]string, error) {
+		labels := make(map[string]string)
+		pprof.ForLabels(ctx, func(key, value string) bool {
+			labels[key] = value
+			return true
+		})
+		return labels, nil
+	}, WithName("db"))
+
+	labels, err := l.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if labels["lazy"] != "db" || labels["lazy.attempt"] != "1" {
+		t.Fatalf("got labels %v, want lazy=db and lazy.attempt=1", labels)
+	}
+}
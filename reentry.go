@@ -0,0 +1,32 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// reentryKey marks the context passed to the initialization function of the
+// Lazy it holds.
+type reentryKey struct {
+	l any
+}
+
+// markReentry returns a copy of ctx that identifies it as belonging to an
+// initialization of l.
+func (l *Lazy[T]) markReentry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reentryKey{l}, true)
+}
+
+// checkReentry returns an error wrapping ErrReentrant if ctx was derived from
+// the context of an initialization of l, or panics with it in debug mode.
+func (l *Lazy[T]) checkReentry(ctx context.Context) error {
+	if ctx.Value(reentryKey{l}) == nil {
+		return nil
+	}
+	err := fmt.Errorf("%w: initialization of %v called itself", ErrReentrant, reflect.TypeFor[T]())
+	if l.opts.debug {
+		panic(err)
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReentrantGetReturnsError(t *testing.T) {
+	var l *Lazy[int]
+	l = New(func(ctx context.Context) (int, error) {
+		return l.Get(ctx)
+	})
+
+	if _, err := l.Get(context.Background()); !errors.Is(err, ErrReentrant) {
+		t.Fatalf("got error %v, want %v", err, ErrReentrant)
+	}
+}
+
+func TestReentrantGetThroughOtherLazy(t *testing.T) {
+	var a, b *Lazy[int]
+	a = New(func(ctx context.Context) (int, error) {
+		return b.Get(ctx)
+	})
+	b = New(func(ctx context.Context) (int, error) {
+		return a.Get(ctx)
+	})
+
+	if _, err := a.Get(context.Background()); !errors.Is(err, ErrReentrant) {
+		t.Fatalf("got error %v, want %v", err, ErrReentrant)
+	}
+}
+
+func TestNestedDistinctLazies(t *testing.T) {
+	inner := New(func(ctx context.Context) (int, error) {
+		return 1, nil
+	})
+	outer := New(func(ctx context.Context) (int, error) {
+		v, err := inner.Get(ctx)
+		return v + 1, err
+	})
+
+	result, err := outer.Get(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 2 {
+		t.Fatalf("got %d, want 2", result)
+	}
+}
+
+func TestReentrantGetPanicsInDebugMode(t *testing.T) {
+	var l *Lazy[int]
+	l = New(func(ctx context.Context) (int, error) {
+		return l.Get(ctx)
+	}, WithDebug())
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, ErrReentrant) {
+			t.Fatalf("got panic %v, want %v", err, ErrReentrant)
+		}
+	}()
+	l.Get(context.Background())
+}
@@ -0,0 +1,50 @@
+package lazy
+
+import (
+	"context"
+	"time"
+)
+
+// hit records a read of s, the value of l for e, and schedules its refresh if
+// the read makes the key hot.
+func (m *Map[K, V]) hit(e *mapEntry[K, V], l *Lazy[stamped[V]], s stamped[V]) {
+	n := m.opts.refreshHits
+	if n <= 0 || s.expires.IsZero() || e.hits.Add(1) != int64(n) {
+		return
+	}
+
+	sh := m.shard(e.hash)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if e.elem == nil || e.l != l || e.refresh != nil || m.closed.Load() {
+		return
+	}
+	d := time.Until(s.expires.Add(-m.opts.refreshWindow))
+	e.refresh = time.AfterFunc(max(d, 0), func() { m.refreshAhead(e, l) })
+}
+
+// refreshAhead reloads the value of l for e in the background, serving the
+// current value until the reload finishes, unless the value has been replaced
+// or too many refreshes are running.
+func (m *Map[K, V]) refreshAhead(e *mapEntry[K, V], l *Lazy[stamped[V]]) {
+	sh := m.shard(e.hash)
+	sh.mu.Lock()
+	if e.elem == nil || e.l != l || m.closed.Load() {
+		sh.mu.Unlock()
+		return
+	}
+	e.refresh = nil
+	sh.mu.Unlock()
+
+	if m.refreshing != nil {
+		select {
+		case m.refreshing <- struct{}{}:
+			defer func() { <-m.refreshing }()
+		default:
+			return
+		}
+	}
+	if l = m.expire(e, l, true); l != nil {
+		l.TryGet(context.Background())
+	}
+}
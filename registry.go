@@ -0,0 +1,70 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// A Handle is the part of a Lazy that does not depend on its value type, so
+// that lazies of different types can be managed together by a registry.
+type Handle interface {
+	State() State
+	Prime(ctx context.Context) error
+	Invalidate()
+}
+
+var registry = struct {
+	mu      sync.RWMutex
+	handles map[string]Handle
+}{handles: make(map[string]Handle)}
+
+// Register adds h, typically a Lazy, to the process-wide registry under name,
+// so that it can be inspected, primed and invalidated by name from anywhere in
+// the program. It panics if name is already registered.
+func Register(name string, h Handle) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, ok := registry.handles[name]; ok {
+		panic(fmt.Sprintf("lazy: Register called twice for %q", name))
+	}
+	registry.handles[name] = h
+}
+
+// Unregister removes name from the registry, if present.
+func Unregister(name string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.handles, name)
+}
+
+// Lookup returns the Handle registered under name, if any.
+func Lookup(name string) (Handle, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	h, ok := registry.handles[name]
+	return h, ok
+}
+
+// Registered returns an iterator over the registered names and handles, in
+// name order. It iterates over the registry as of when iteration starts.
+func Registered() iter.Seq2[string, Handle] {
+	return func(yield func(string, Handle) bool) {
+		registry.mu.RLock()
+		names := slices.Sorted(maps.Keys(registry.handles))
+		handles := make([]Handle, len(names))
+		for i, name := range names {
+			handles[i] = registry.handles[name]
+		}
+		registry.mu.RUnlock()
+
+		for i, name := range names {
+			if !yield(name, handles[i]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package lazy
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	a := New(func(ctx context.Context) (int, error) { return 1, nil })
+	b := New(func(ctx context.Context) (string, error) { return "b", nil })
+	Register("test.b", b)
+	Register("test.a", a)
+	defer Unregister("test.a")
+	defer Unregister("test.b")
+
+	var names []string
+	for name, h := range Registered() {
+		if name == "test.a" || name == "test.b" {
+			names = append(names, name)
+			if h.State() != StateIdle {
+				t.Fatalf("%s: got state %v, want %v", name, h.State(), StateIdle)
+			}
+		}
+	}
+	if len(names) != 2 || names[0] != "test.a" || names[1] != "test.b" {
+		t.Fatalf("got names %q, want [test.a test.b]", names)
+	}
+
+	h, ok := Lookup("test.a")
+	if !ok {
+		t.Fatal("Lookup did not find a registered name")
+	}
+	if err := h.Prime(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if a.State() != StateReady {
+		t.Fatalf("got state %v after Prime, want %v", a.State(), StateReady)
+	}
+	h.Invalidate()
+	if a.State() != StateIdle {
+		t.Fatalf("got state %v after Invalidate, want %v", a.State(), StateIdle)
+	}
+
+	if _, ok := Lookup("test.missing"); ok {
+		t.Fatal("Lookup found an unregistered name")
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	l := New(func(ctx context.Context) (int, error) { return 1, nil })
+	Register("test.dup", l)
+	defer Unregister("test.dup")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Register did not panic for a duplicate name")
+		}
+	}()
+	Register("test.dup", l)
+}
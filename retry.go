@@ -0,0 +1,110 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// retryState tracks failed initializations for the retry policy.
+type retryState struct {
+	fgen      uint64 // of the initialization function the state is about
+	failures  int
+	frozen    error
+	lastErr   error
+	notBefore time.Time
+	start     time.Time
+}
+
+// backoff computes the delay between retries.
+type backoff struct {
+	base   time.Duration
+	limit  time.Duration
+	jitter float64
+}
+
+// delay returns the delay after the nth consecutive failure. Without a limit,
+// it stops doubling before it would overflow a time.Duration.
+func (b backoff) delay(n int) time.Duration {
+	if b.base <= 0 {
+		return 0
+	}
+	d := b.base
+	for i := 1; i < n && (b.limit <= 0 || d < b.limit) && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if b.limit > 0 {
+		d = min(d, b.limit)
+	}
+	return d - time.Duration(rand.Float64()*b.jitter*float64(d))
+}
+
+// hasRetryPolicy reports whether o limits the retries of failed
+// initializations, so that their failures must be remembered.
+func (o *options) hasRetryPolicy() bool {
+	return o.maxAttempts > 0 || o.backoff.base > 0 || o.retryBudget > 0 || o.permanent != nil
+}
+
+// checkRetry reports whether the retry policy allows calling f. It must be
+// called with sem held.
+func (l *Lazy[T]) checkRetry() error {
+	if l.retry.frozen != nil {
+		return l.retry.frozen
+	}
+	now := time.Now()
+	if l.retry.start.IsZero() {
+		l.retry.start = now
+	}
+	if l.budgetExhausted(now) {
+		return l.freeze(l.retry.lastErr)
+	}
+	if now.Before(l.retry.notBefore) {
+		return &RetryLaterError{Err: l.retry.lastErr, Wait: l.retry.notBefore.Sub(now)}
+	}
+	return nil
+}
+
+// budgetExhausted reports whether the retry budget has run out at now.
+func (l *Lazy[T]) budgetExhausted(now time.Time) bool {
+	d := l.opts.retryBudget
+	return d > 0 && l.retry.failures > 0 && now.Sub(l.retry.start) >= d
+}
+
+// freeze stops all future calls of f, reporting an error that wraps err.
+func (l *Lazy[T]) freeze(err error) error {
+	l.retry.frozen = fmt.Errorf("%w after %d attempts: %w", ErrFrozen, l.retry.failures, err)
+	l.mu.Lock()
+	if l.fgen == l.retry.fgen {
+		l.f = nil // Allow f to be garbage collected.
+	}
+	l.mu.Unlock()
+	return l.retry.frozen
+}
+
+// recordFailure updates the retry policy state after f returned err and
+// returns the error to report to the caller. It must be called with sem held.
+func (l *Lazy[T]) recordFailure(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	l.retry.failures++
+	if l.opts.permanent != nil && l.opts.permanent(err) {
+		return l.freeze(err)
+	}
+	if n := l.opts.maxAttempts; n > 0 && l.retry.failures >= n || l.budgetExhausted(time.Now()) {
+		return l.freeze(err)
+	}
+	l.retry.lastErr = err
+	d := l.opts.backoff.delay(l.retry.failures)
+	var hint RetryHint
+	if errors.As(err, &hint) {
+		d = max(d, hint.RetryAfter())
+	}
+	if d > 0 {
+		l.retry.notBefore = time.Now().Add(d)
+	}
+	return err
+}
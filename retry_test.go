@@ -0,0 +1,276 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestWithMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	errFail := errors.New("fail")
+
+	l := New(func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		return 0, errFail
+	}, WithMaxAttempts(2))
+
+	if _, err := l.Get(context.Background()); !errors.Is(err, errFail) || errors.Is(err, ErrFrozen) {
+		t.Fatalf("got error %v, want %v", err, errFail)
+	}
+
+	// The second failure freezes the lazy.
+	for range 3 {
+		_, err := l.Get(context.Background())
+		if !errors.Is(err, ErrFrozen) {
+			t.Fatalf("got error %v, want %v", err, ErrFrozen)
+		}
+		if !errors.Is(err, errFail) {
+			t.Fatalf("got error %v, want wrapped %v", err, errFail)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("function called %d times, want 2", got)
+	}
+}
+
+func TestWithMaxAttempts_IgnoresCancelledCalls(t *testing.T) {
+	var calls atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l := New(func(ctx context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			// The caller gives up during the first attempt.
+			cancel()
+			return 0, ctx.Err()
+		}
+		return 3, nil
+	}, WithMaxAttempts(1))
+
+	if _, err := l.Get(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+
+	result, err := l.Get(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 3 {
+		t.Fatalf("got %d, want 3", result)
+	}
+}
+
+func TestWithBackoff(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		errFail := errors.New("fail")
+
+		l := New(func(ctx context.Context) (int, error) {
+			if calls.Add(1) <= 2 {
+				return 0, errFail
+			}
+			return 1, nil
+		}, WithBackoff(time.Second, 3*time.Second, 0))
+
+		if _, err := l.Get(context.Background()); !errors.Is(err, errFail) {
+			t.Fatalf("got error %v, want %v", err, errFail)
+		}
+
+		// During the backoff window callers receive the last error.
+		time.Sleep(time.Second - time.Nanosecond)
+		if _, err := l.Get(context.Background()); !errors.Is(err, errFail) {
+			t.Fatalf("got error %v, want %v", err, errFail)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("function called %d times, want 1", got)
+		}
+
+		// The second failure doubles the delay.
+		time.Sleep(time.Nanosecond)
+		l.Get(context.Background())
+		time.Sleep(2*time.Second - time.Nanosecond)
+		l.Get(context.Background())
+		if got := calls.Load(); got != 2 {
+			t.Fatalf("function called %d times, want 2", got)
+		}
+
+		time.Sleep(time.Nanosecond)
+		result, err := l.Get(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != 1 {
+			t.Fatalf("got %d, want 1", result)
+		}
+	})
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := backoff{base: time.Second, limit: 5 * time.Second}
+	for n, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := b.delay(n + 1); got != want {
+			t.Errorf("delay(%d) = %v, want %v", n+1, got, want)
+		}
+	}
+
+	b.jitter = 0.5
+	for range 100 {
+		if d := b.delay(1); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("delay(1) = %v, want between 500ms and 1s", d)
+		}
+	}
+}
+
+func TestBackoffDelay_NoLimit(t *testing.T) {
+	b := backoff{base: time.Second}
+	prev := b.delay(1)
+	for n := 2; n <= 100; n++ {
+		d := b.delay(n)
+		if d < prev {
+			t.Fatalf("delay(%d) = %v, less than delay(%d) = %v", n, d, n-1, prev)
+		}
+		prev = d
+	}
+	if want := time.Duration(math.MaxInt64 / 2); prev < want {
+		t.Fatalf("delay(100) = %v, want at least %v", prev, want)
+	}
+}
+
+func TestWithRetryBudget(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		errFail := errors.New("fail")
+
+		l := New(func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			time.Sleep(time.Second)
+			return 0, errFail
+		}, WithRetryBudget(5*time.Second))
+
+		for range 4 {
+			if _, err := l.Get(context.Background()); errors.Is(err, ErrFrozen) {
+				t.Fatalf("frozen before budget was exhausted: %v", err)
+			}
+		}
+
+		// The fifth failure exhausts the budget.
+		for range 2 {
+			_, err := l.Get(context.Background())
+			if !errors.Is(err, ErrFrozen) || !errors.Is(err, errFail) {
+				t.Fatalf("got error %v, want %v wrapping %v", err, ErrFrozen, errFail)
+			}
+		}
+		if got := calls.Load(); got != 5 {
+			t.Fatalf("function called %d times, want 5", got)
+		}
+	})
+}
+
+func TestWithRetryBudget_ExhaustedDuringBackoff(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		errFail := errors.New("fail")
+
+		l := New(func(ctx context.Context) (int, error) {
+			return 0, errFail
+		}, WithRetryBudget(time.Second), WithBackoff(time.Minute, 0, 0))
+
+		l.Get(context.Background())
+		time.Sleep(time.Second)
+
+		if _, err := l.Get(context.Background()); !errors.Is(err, ErrFrozen) {
+			t.Fatalf("got error %v, want %v", err, ErrFrozen)
+		}
+	})
+}
+
+func TestWithPermanentErrors(t *testing.T) {
+	var calls atomic.Int32
+	errNotFound := errors.New("not found")
+	errUnavailable := errors.New("unavailable")
+
+	l := New(func(ctx context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			return 0, errUnavailable
+		}
+		return 0, fmt.Errorf("lookup: %w", errNotFound)
+	}, WithPermanentErrors(PermanentErrors(errNotFound)))
+
+	// Retryable errors are retried.
+	if _, err := l.Get(context.Background()); !errors.Is(err, errUnavailable) || errors.Is(err, ErrFrozen) {
+		t.Fatalf("got error %v, want %v", err, errUnavailable)
+	}
+
+	// Permanent errors are sticky.
+	for range 2 {
+		_, err := l.Get(context.Background())
+		if !errors.Is(err, errNotFound) || !errors.Is(err, ErrFrozen) {
+			t.Fatalf("got error %v, want %v wrapping %v", err, ErrFrozen, errNotFound)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("function called %d times, want 2", got)
+	}
+}
+
+// rateLimitError is a RetryHint.
+type rateLimitError struct {
+	after time.Duration
+}
+
+func (e rateLimitError) Error() string             { return "rate limited" }
+func (e rateLimitError) RetryAfter() time.Duration { return e.after }
+
+func TestRetryHint(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		l := New(func(ctx context.Context) (int, error) {
+			if calls.Add(1) == 1 {
+				return 0, fmt.Errorf("fetching: %w", rateLimitError{time.Minute})
+			}
+			return 1, nil
+		})
+
+		l.Get(context.Background())
+		time.Sleep(20 * time.Second)
+
+		_, err := l.Get(context.Background())
+		var later *RetryLaterError
+		if !errors.As(err, &later) || later.RetryAfter() != 40*time.Second {
+			t.Fatalf("got error %v, want a RetryLaterError with 40s remaining", err)
+		}
+		if !errors.As(err, new(rateLimitError)) {
+			t.Fatalf("got error %v, want it to wrap the original error", err)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("initialization called %d times during the delay, want 1", got)
+		}
+
+		time.Sleep(40 * time.Second)
+		if v, err := l.Get(context.Background()); v != 1 || err != nil {
+			t.Fatalf("got (%d, %v) after the delay, want (1, nil)", v, err)
+		}
+	})
+}
+
+func TestRetryHint_Map(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		m := NewMap(func(ctx context.Context, key string) (int, error) {
+			calls.Add(1)
+			return 0, rateLimitError{time.Minute}
+		})
+
+		// The delay is enforced even though failed keys are otherwise
+		// released.
+		m.Get(context.Background(), "a")
+		m.Get(context.Background(), "a")
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("load called %d times during the delay, want 1", got)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// A scope holds the lazy values memoized for the lifetime of a context.
+type scope struct {
+	mu     sync.Mutex
+	values map[any]any // of *Lazy[T]; nil once closed
+}
+
+type scopeKey struct{}
+
+// NewScope returns a copy of ctx carrying a new scope for InCtx. Values
+// memoized in the scope are released when the scope's context is no longer
+// referenced, which makes it suitable for per-request memoization.
+func NewScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{values: make(map[any]any)})
+}
+
+// InCtx returns the value identified by key in the scope carried by ctx,
+// calling f to initialize it on first use. Like Func, f is called at most once
+// successfully per scope, and errors are retried on the next call. The key
+// must be comparable and should be of an unexported type to avoid collisions,
+// as with context.WithValue.
+//
+// If ctx carries no scope, or its scope has been closed, InCtx calls f without
+// memoizing its result.
+func InCtx[T any](ctx context.Context, key any, f func(context.Context) (T, error)) (T, error) {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return f(ctx)
+	}
+
+	s.mu.Lock()
+	if s.values == nil {
+		s.mu.Unlock()
+		return f(ctx)
+	}
+	v, ok := s.values[key]
+	if !ok {
+		v = New(f)
+		s.values[key] = v
+	}
+	s.mu.Unlock()
+
+	l, ok := v.(*Lazy[T])
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("lazy: scope key %v holds %T, not *Lazy[%v]", key, v, reflect.TypeFor[T]())
+	}
+	return l.Get(ctx)
+}
+
+// close releases the values memoized in s. Later calls of InCtx with the scope
+// no longer memoize.
+func (s *scope) close() {
+	s.mu.Lock()
+	s.values = nil
+	s.mu.Unlock()
+}
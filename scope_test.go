@@ -0,0 +1,68 @@
+package lazy
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+type userKey struct{}
+
+func TestInCtx_MemoizesPerScope(t *testing.T) {
+	var calls atomic.Int32
+	lookup := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return "alice", nil
+	}
+
+	ctx := NewScope(context.Background())
+	for range 3 {
+		user, err := InCtx(ctx, userKey{}, lookup)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if user != "alice" {
+			t.Fatalf("got %q, want %q", user, "alice")
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("function called %d times, want 1", got)
+	}
+
+	// A new scope computes the value again.
+	InCtx(NewScope(context.Background()), userKey{}, lookup)
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("function called %d times, want 2", got)
+	}
+}
+
+func TestInCtx_WithoutScope(t *testing.T) {
+	var calls atomic.Int32
+	lookup := func(ctx context.Context) (int, error) {
+		return int(calls.Add(1)), nil
+	}
+
+	for want := 1; want <= 2; want++ {
+		got, err := InCtx(context.Background(), userKey{}, lookup)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Fatalf("got %d, want %d", got, want)
+		}
+	}
+}
+
+func TestInCtx_TypeMismatch(t *testing.T) {
+	ctx := NewScope(context.Background())
+	InCtx(ctx, userKey{}, func(ctx context.Context) (string, error) {
+		return "alice", nil
+	})
+
+	_, err := InCtx(ctx, userKey{}, func(ctx context.Context) (int, error) {
+		return 1, nil
+	})
+	if err == nil {
+		t.Fatal("expected error for mismatched type")
+	}
+}
@@ -0,0 +1,93 @@
+package lazy
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// A semaphore is a binary semaphore whose waiters acquire it in FIFO order.
+// Releasing the semaphore while callers are waiting hands it directly to the
+// caller that has waited longest, so no caller can be overtaken by callers
+// that arrived later. The zero value is an unheld semaphore.
+type semaphore struct {
+	// maxWaiters, if positive, limits the number of waiting callers.
+	maxWaiters int
+
+	mu      sync.Mutex
+	held    bool
+	waiters list.List // of chan struct{}
+}
+
+// tryAcquire acquires s without blocking, reporting whether it succeeded.
+// It never overtakes waiting callers.
+func (s *semaphore) tryAcquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held {
+		return false
+	}
+	s.held = true
+	return true
+}
+
+// acquire acquires s, blocking until it is available or ctx is done. It
+// returns the cause of ctx if ctx is done first, or ErrBusy without blocking
+// if the maximum number of callers is already waiting.
+func (s *semaphore) acquire(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.held {
+		s.held = true
+		s.mu.Unlock()
+		return nil
+	}
+	if s.maxWaiters > 0 && s.waiters.Len() >= s.maxWaiters {
+		s.mu.Unlock()
+		return ErrBusy
+	}
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// Acquired after ctx was done; pass it on to the next waiter.
+			s.mu.Unlock()
+			s.release()
+		default:
+			s.waiters.Remove(elem)
+			s.mu.Unlock()
+		}
+		return context.Cause(ctx)
+	}
+}
+
+// release releases s, handing it to the longest-waiting caller, if any.
+func (s *semaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if front := s.waiters.Front(); front != nil {
+		close(s.waiters.Remove(front).(chan struct{}))
+		return
+	}
+	s.held = false
+}
+
+// busy reports whether s is held.
+func (s *semaphore) busy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.held
+}
+
+// waiting returns the number of callers waiting to acquire s.
+func (s *semaphore) waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiters.Len()
+}
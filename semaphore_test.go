@@ -0,0 +1,94 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+)
+
+func TestSemaphore_FIFO(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var s semaphore
+		if !s.tryAcquire() {
+			t.Fatal("tryAcquire failed on unheld semaphore")
+		}
+
+		order := make(chan int, 5)
+		for i := range 5 {
+			go func() {
+				if err := s.acquire(context.Background()); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				order <- i
+				s.release()
+			}()
+			// Let each goroutine queue up before starting the next.
+			synctest.Wait()
+		}
+
+		s.release()
+		for want := range 5 {
+			if got := <-order; got != want {
+				t.Fatalf("waiter %d acquired the semaphore in position %d", got, want)
+			}
+		}
+	})
+}
+
+func TestSemaphore_CancelledWaiterIsSkipped(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var s semaphore
+		s.tryAcquire()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error, 1)
+		go func() { errs <- s.acquire(ctx) }()
+		synctest.Wait()
+
+		acquired := make(chan struct{})
+		go func() {
+			s.acquire(context.Background())
+			close(acquired)
+		}()
+		synctest.Wait()
+
+		cancel()
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Fatalf("got error %v, want %v", err, context.Canceled)
+		}
+
+		s.release()
+		<-acquired
+		if s.tryAcquire() {
+			t.Fatal("tryAcquire succeeded on held semaphore")
+		}
+	})
+}
+
+func TestLazy_RetriesInFIFOOrder(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		proceed := make(chan struct{})
+		order := make(chan int, 3)
+
+		l := New(func(ctx context.Context) (int, error) {
+			<-proceed
+			order <- ctx.Value(orderKey{}).(int)
+			return 0, errors.New("fail")
+		})
+
+		for i := range 3 {
+			go l.Get(context.WithValue(context.Background(), orderKey{}, i))
+			synctest.Wait()
+		}
+
+		close(proceed)
+		for want := range 3 {
+			if got := <-order; got != want {
+				t.Fatalf("caller %d retried in position %d", got, want)
+			}
+		}
+	})
+}
+
+type orderKey struct{}
@@ -0,0 +1,121 @@
+package lazy
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// Seq memoizes the sequence returned by f: the returned function returns a
+// sequence that replays the elements produced so far and produces the next
+// ones on demand, so that several consumers, even concurrent ones, can range
+// over it while f and its sequence run once. Elements are produced as the
+// consumers that are furthest along ask for them, so a consumer that stops
+// early does not make the sequence produce the rest; the sequence is then
+// suspended until a consumer asks for more, and its resources are held until
+// it ends. If the sequence panics, the panic propagates to the consumer that
+// asked for the next element and the sequence ends there for all consumers.
+//
+// f is called with the context of the first consumer, without its
+// cancellation, so that a consumer that gives up does not truncate the cached
+// sequence for the others.
+func Seq[T any](f func(context.Context) iter.Seq[T]) func(context.Context) iter.Seq[T] {
+	r := &replay[T]{start: func(ctx context.Context) func() (T, bool) {
+		next, _ := iter.Pull(f(ctx))
+		return next
+	}}
+	return func(ctx context.Context) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			for i := 0; ; i++ {
+				v, ok := r.at(ctx, i)
+				if !ok || !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Seq2 is like Seq for sequences of pairs, such as keys and values or values
+// and errors. A producer that fails midway typically yields its error as the
+// last pair: it is cached like the others, so that each consumer that reaches
+// it receives the error, and the elements before it, in turn.
+func Seq2[K, V any](f func(context.Context) iter.Seq2[K, V]) func(context.Context) iter.Seq2[K, V] {
+	r := &replay[Tuple2[K, V]]{start: func(ctx context.Context) func() (Tuple2[K, V], bool) {
+		next, _ := iter.Pull2(f(ctx))
+		return func() (Tuple2[K, V], bool) {
+			k, v, ok := next()
+			return Tuple2[K, V]{k, v}, ok
+		}
+	}}
+	return func(ctx context.Context) iter.Seq2[K, V] {
+		return func(yield func(K, V) bool) {
+			for i := 0; ; i++ {
+				t, ok := r.at(ctx, i)
+				if !ok || !yield(t.V1, t.V2) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// A replay caches the elements of a sequence pulled once for all consumers.
+type replay[T any] struct {
+	// start starts the sequence, returning the function that pulls its
+	// next element.
+	start func(context.Context) func() (T, bool)
+
+	// pull serializes the calls of next.
+	pull sync.Mutex
+	next func() (T, bool)
+
+	mu    sync.Mutex
+	items []T
+	done  bool
+}
+
+// at returns the element at index i, pulling it with ctx if it has not been
+// produced yet, or false if the sequence ends before it.
+func (r *replay[T]) at(ctx context.Context, i int) (T, bool) {
+	for {
+		if v, ok, known := r.cached(i); known {
+			return v, ok
+		}
+		r.pullNext(ctx, i)
+	}
+}
+
+// cached returns the element at index i and whether it exists, if that is
+// known without pulling.
+func (r *replay[T]) cached(i int) (v T, ok, known bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i < len(r.items) {
+		return r.items[i], true, true
+	}
+	return v, false, r.done
+}
+
+// pullNext pulls the element at index i, unless another consumer has done so
+// meanwhile.
+func (r *replay[T]) pullNext(ctx context.Context, i int) {
+	r.pull.Lock()
+	defer r.pull.Unlock()
+	if _, _, known := r.cached(i); known {
+		return
+	}
+	if r.next == nil {
+		r.next = r.start(context.WithoutCancel(ctx))
+		r.start = nil
+	}
+	v, ok := r.next()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !ok {
+		r.done = true
+		r.next = nil
+		return
+	}
+	r.items = append(r.items, v)
+}
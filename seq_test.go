@@ -0,0 +1,86 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestSeq(t *testing.T) {
+	calls := 0
+	produced := 0
+	seq := Seq(func(ctx context.Context) iter.Seq[int] {
+		calls++
+		return func(yield func(int) bool) {
+			for i := range 5 {
+				produced++
+				if !yield(i) {
+					return
+				}
+			}
+		}
+	})
+
+	for v := range seq(context.Background()) {
+		if v == 1 {
+			break
+		}
+	}
+	if produced != 2 {
+		t.Fatalf("produced %d elements for a consumer stopping at the second, want 2", produced)
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]int, 4)
+	for i := range results {
+		wg.Go(func() {
+			results[i] = slices.Collect(seq(context.Background()))
+		})
+	}
+	wg.Wait()
+	for _, got := range results {
+		if !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+			t.Fatalf("got %v, want [0 1 2 3 4]", got)
+		}
+	}
+	if calls != 1 || produced != 5 {
+		t.Fatalf("got %d calls producing %d elements, want 1 producing 5", calls, produced)
+	}
+}
+
+func TestSeq2(t *testing.T) {
+	errFail := errors.New("fail")
+	calls := 0
+	seq := Seq2(func(ctx context.Context) iter.Seq2[int, error] {
+		calls++
+		return func(yield func(int, error) bool) {
+			for i := range 2 {
+				if !yield(i, nil) {
+					return
+				}
+			}
+			yield(0, errFail)
+		}
+	})
+
+	for range 2 {
+		var got []int
+		var err error
+		for v, e := range seq(context.Background()) {
+			if e != nil {
+				err = e
+				break
+			}
+			got = append(got, v)
+		}
+		if !slices.Equal(got, []int{0, 1}) || !errors.Is(err, errFail) {
+			t.Fatalf("got %v, %v, want [0 1], %v", got, err, errFail)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("got %d calls, want 1", calls)
+	}
+}
@@ -0,0 +1,40 @@
+package lazy
+
+import "context"
+
+// SetFunc replaces the initialization function of l with f and discards the
+// cached value, if any, as Invalidate does, so that the next Get calls f. The
+// retry state and sticky panic of the previous function are forgotten with it.
+// An initialization in progress completes with the previous function: its
+// callers receive its outcome, but it is not cached. If its value has a
+// cleanup function, as with NewWithCleanup, nothing would release the uncached
+// value, so it is released at once and the callers receive the outcome of f
+// instead. SetFunc has no effect once l is closed.
+func (l *Lazy[T]) SetFunc(f func(context.Context) (T, error)) {
+	l.mu.Lock()
+	if l.closed.Load() {
+		l.mu.Unlock()
+		return
+	}
+	l.f = noCleanup(f)
+	l.fgen++
+	l.value.Store(nil)
+	l.failure.Store(nil)
+	l.degraded.Store(false)
+	cleanup := l.cleanup
+	l.holdLocked(nil, nil)
+	l.mu.Unlock()
+	runCleanup(cleanup)
+}
+
+// resetRetry forgets the retry state and sticky panic of l if they are about a
+// function that SetFunc has since replaced. It must be called with sem held.
+func (l *Lazy[T]) resetRetry() {
+	l.mu.Lock()
+	gen := l.fgen
+	l.mu.Unlock()
+	if l.retry.fgen != gen {
+		l.retry = retryState{fgen: gen}
+		l.panicked = nil
+	}
+}
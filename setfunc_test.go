@@ -0,0 +1,94 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"testing/synctest"
+)
+
+func TestLazy_SetFunc(t *testing.T) {
+	l := New(func(ctx context.Context) (string, error) { return "old", nil })
+	if v, _ := l.Get(context.Background()); v != "old" {
+		t.Fatalf("got %q, want old", v)
+	}
+
+	l.SetFunc(func(ctx context.Context) (string, error) { return "new", nil })
+	if l.State() != StateIdle {
+		t.Fatalf("got state %v after SetFunc, want %v", l.State(), StateIdle)
+	}
+	if v, _ := l.Get(context.Background()); v != "new" {
+		t.Fatalf("got %q after SetFunc, want new", v)
+	}
+}
+
+func TestLazy_SetFuncInFlight(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		proceed := make(chan struct{})
+		l := New(func(ctx context.Context) (string, error) {
+			<-proceed
+			return "old", nil
+		})
+
+		got := make(chan string)
+		go func() {
+			v, _ := l.Get(context.Background())
+			got <- v
+		}()
+		synctest.Wait()
+		l.SetFunc(func(ctx context.Context) (string, error) { return "new", nil })
+		close(proceed)
+		if v := <-got; v != "old" {
+			t.Fatalf("in-flight Get got %q, want old", v)
+		}
+		if v, _ := l.Get(context.Background()); v != "new" {
+			t.Fatalf("got %q after the in-flight Get, want new", v)
+		}
+	})
+}
+
+func TestLazy_SetFuncInFlightCleanup(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		proceed := make(chan struct{})
+		var cleaned []string
+		l := NewWithCleanup(func(ctx context.Context) (string, func(context.Context) error, error) {
+			<-proceed
+			return "old", func(context.Context) error {
+				cleaned = append(cleaned, "old")
+				return nil
+			}, nil
+		})
+
+		got := make(chan string)
+		go func() {
+			v, _ := l.Get(context.Background())
+			got <- v
+		}()
+		synctest.Wait()
+		l.SetFunc(func(ctx context.Context) (string, error) { return "new", nil })
+		close(proceed)
+
+		// The value of the replaced function would leak if it were served.
+		if v := <-got; v != "new" {
+			t.Fatalf("in-flight Get got %q, want new", v)
+		}
+		if !slices.Equal(cleaned, []string{"old"}) {
+			t.Fatalf("got cleaned %v, want [old]", cleaned)
+		}
+	})
+}
+
+func TestLazy_SetFuncFrozen(t *testing.T) {
+	errFail := errors.New("fail")
+	l := New(func(ctx context.Context) (int, error) { return 0, errFail }, WithMaxAttempts(1))
+	l.Get(context.Background())
+	if _, err := l.Get(context.Background()); !errors.Is(err, ErrFrozen) {
+		t.Fatalf("got error %v, want %v", err, ErrFrozen)
+	}
+
+	l.SetFunc(func(ctx context.Context) (int, error) { return 1, nil })
+	if v, err := l.Get(context.Background()); err != nil || v != 1 {
+		t.Fatalf("got %v, %v after SetFunc, want 1, nil", v, err)
+	}
+}
@@ -0,0 +1,25 @@
+package lazy
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// singletons holds the Lazy returned by Of for each type.
+var singletons sync.Map // of reflect.Type to *Lazy[T]
+
+// Of returns the process-wide Lazy for type T, creating it with f and opts on
+// the first call for T. Later calls return the same Lazy and ignore their
+// arguments, so independent packages needing the same expensive value, such
+// as a parsed schema, share a single initialization without passing it
+// around. Distinct types, including defined types with the same underlying
+// type, have distinct lazies.
+func Of[T any](f func(context.Context) (T, error), opts ...Option) *Lazy[T] {
+	t := reflect.TypeFor[T]()
+	if l, ok := singletons.Load(t); ok {
+		return l.(*Lazy[T])
+	}
+	l, _ := singletons.LoadOrStore(t, New(f, opts...))
+	return l.(*Lazy[T])
+}
@@ -0,0 +1,31 @@
+package lazy
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	type schema struct{ version int }
+	type otherSchema schema
+
+	a := Of(func(ctx context.Context) (*schema, error) {
+		return &schema{1}, nil
+	})
+	b := Of(func(ctx context.Context) (*schema, error) {
+		return &schema{2}, nil
+	})
+	if a != b {
+		t.Fatal("Of returned distinct lazies for the same type")
+	}
+	if v := a.MustGet(context.Background()); v.version != 1 {
+		t.Fatalf("got version %d, want 1", v.version)
+	}
+
+	c := Of(func(ctx context.Context) (*otherSchema, error) {
+		return &otherSchema{3}, nil
+	})
+	if v := c.MustGet(context.Background()); v.version != 3 {
+		t.Fatalf("got version %d for another type, want 3", v.version)
+	}
+}
@@ -0,0 +1,49 @@
+package lazy
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// A Slice is a fixed number of lazily initialized elements, each computed on
+// first access with its own single flight and retries, as for resources
+// indexed by shard of which only some are ever used. A Slice is safe for
+// concurrent use by multiple goroutines.
+type Slice[T any] struct {
+	f    func(context.Context, int) (T, error)
+	opts []Option
+	ls   []atomic.Pointer[Lazy[T]]
+}
+
+// NewSlice returns a Slice of n elements, where element i is initialized by
+// calling f with i, as by a Lazy created with opts.
+func NewSlice[T any](n int, f func(ctx context.Context, i int) (T, error), opts ...Option) *Slice[T] {
+	return &Slice[T]{f: f, opts: opts, ls: make([]atomic.Pointer[Lazy[T]], n)}
+}
+
+// Len returns the number of elements of s.
+func (s *Slice[T]) Len() int {
+	return len(s.ls)
+}
+
+// Get returns element i of s, initializing it if needed, as Lazy.Get does. It
+// panics if i is out of range.
+func (s *Slice[T]) Get(ctx context.Context, i int) (T, error) {
+	return s.At(i).Get(ctx)
+}
+
+// At returns the Lazy of element i of s, to inspect or invalidate it. It
+// panics if i is out of range.
+func (s *Slice[T]) At(i int) *Lazy[T] {
+	p := &s.ls[i]
+	if l := p.Load(); l != nil {
+		return l
+	}
+	l := New(func(ctx context.Context) (T, error) {
+		return s.f(ctx, i)
+	}, s.opts...)
+	if p.CompareAndSwap(nil, l) {
+		return l
+	}
+	return p.Load()
+}
@@ -0,0 +1,45 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestSlice(t *testing.T) {
+	errFail := errors.New("fail")
+	var mu sync.Mutex
+	calls := make(map[int]int)
+	s := NewSlice(4, func(ctx context.Context, i int) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[i]++
+		if i == 2 && calls[i] == 1 {
+			return 0, errFail
+		}
+		return i * 10, nil
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if v, err := s.Get(context.Background(), 1); err != nil || v != 10 {
+				t.Errorf("got %v, %v, want 10, nil", v, err)
+			}
+		})
+	}
+	wg.Wait()
+	if _, err := s.Get(context.Background(), 2); !errors.Is(err, errFail) {
+		t.Fatalf("got error %v, want %v", err, errFail)
+	}
+	if v, err := s.Get(context.Background(), 2); err != nil || v != 20 {
+		t.Fatalf("got %v, %v on retry, want 20, nil", v, err)
+	}
+	if calls[0] != 0 || calls[1] != 1 || calls[2] != 2 {
+		t.Fatalf("got calls %v, want 1 for index 1 and 2 for index 2", calls)
+	}
+	if s.Len() != 4 || s.At(3).State() != StateIdle {
+		t.Fatalf("got length %d and state %v, want 4 and idle", s.Len(), s.At(3).State())
+	}
+}
@@ -0,0 +1,74 @@
+package lazy
+
+import (
+	"bytes"
+	"runtime"
+	"time"
+)
+
+// WithSlowThreshold calls slow once, from another goroutine, if a call of the
+// initialization function runs for longer than d, with the time elapsed so far,
+// so that hung initializations do not block silently. With WithDebug, stack is
+// the stack of the goroutine running the call at that time, and is otherwise
+// nil.
+func WithSlowThreshold(d time.Duration, slow func(elapsed time.Duration, stack []byte)) Option {
+	return func(o *options) {
+		o.slowThreshold = d
+		o.onSlow = slow
+	}
+}
+
+// watchSlow starts watching a call of f run by the calling goroutine, as
+// configured with WithSlowThreshold, and returns a function that stops
+// watching it.
+func (l *Lazy[T]) watchSlow() func() {
+	d, slow := l.opts.slowThreshold, l.opts.onSlow
+	if d <= 0 || slow == nil {
+		return func() {}
+	}
+	start := time.Now()
+	var id []byte
+	if l.opts.debug {
+		id = goroutineID()
+	}
+	t := time.AfterFunc(d, func() {
+		var stack []byte
+		if id != nil {
+			stack = goroutineStack(id)
+		}
+		slow(time.Since(start), stack)
+	})
+	return func() { t.Stop() }
+}
+
+// goroutineID returns the header of the stack of the calling goroutine, such
+// as "goroutine 18 [", which identifies it in the output of runtime.Stack.
+func goroutineID() []byte {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	i := bytes.IndexByte(buf, '[')
+	if i < 0 {
+		return nil
+	}
+	return buf[:i+1]
+}
+
+// goroutineStack returns the stack of the goroutine identified by id, as
+// returned by goroutineID, or nil if it has exited.
+func goroutineStack(id []byte) []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	for stack := range bytes.SplitSeq(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, id) {
+			return stack
+		}
+	}
+	return nil
+}
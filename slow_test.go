@@ -0,0 +1,61 @@
+package lazy
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestWithSlowThreshold(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		elapsed := make(chan time.Duration, 2)
+		l := New(func(ctx context.Context) (int, error) {
+			time.Sleep(3 * time.Second)
+			return 1, nil
+		}, WithSlowThreshold(time.Second, func(d time.Duration, stack []byte) {
+			if stack != nil {
+				t.Error("got a stack without WithDebug")
+			}
+			elapsed <- d
+		}))
+
+		l.Prime(context.Background())
+		if d := <-elapsed; d != time.Second {
+			t.Fatalf("slow called after %v, want 1s", d)
+		}
+		if len(elapsed) != 0 {
+			t.Fatal("slow called more than once")
+		}
+	})
+}
+
+func TestWithSlowThreshold_Fast(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		l := New(func(ctx context.Context) (int, error) { return 1, nil },
+			WithSlowThreshold(time.Second, func(time.Duration, []byte) {
+				t.Error("slow called for a fast initialization")
+			}))
+		l.Prime(context.Background())
+		time.Sleep(2 * time.Second)
+	})
+}
+
+func TestWithSlowThreshold_Stack(t *testing.T) {
+	stacks := make(chan []byte, 1)
+	proceed := make(chan struct{})
+	l := New(func(ctx context.Context) (int, error) {
+		<-proceed
+		return 1, nil
+	}, WithDebug(), WithSlowThreshold(time.Millisecond, func(d time.Duration, stack []byte) {
+		stacks <- stack
+	}))
+
+	go l.Get(context.Background())
+	stack := <-stacks
+	close(proceed)
+	if !bytes.Contains(stack, []byte("TestWithSlowThreshold_Stack.func1")) {
+		t.Fatalf("got stack\n%s\nwant the stack of the initialization", stack)
+	}
+}
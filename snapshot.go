@@ -0,0 +1,172 @@
+package lazy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxSnapshotRecord is the size limit of an encoded snapshot record, so that
+// a corrupt snapshot cannot make Restore allocate unbounded memory.
+const maxSnapshotRecord = 1 << 30
+
+// A snapshotRecord is the encoded form of a Map entry in a snapshot.
+type snapshotRecord[K, V any] struct {
+	Key    K
+	Value  V
+	Loaded time.Time
+	TTL    time.Duration // zero if the value does not expire
+	Tags   []string
+}
+
+// Snapshot writes the cached values of m to w, along with when they were
+// loaded, their TTL and their tags, so that a later Restore, possibly by
+// another process, can warm up a Map with them. Values are encoded with the
+// Codec set with WithCodec. Values that cannot be encoded are skipped;
+// Snapshot then writes the others and returns an error describing the skipped
+// ones. Loads in progress and expired values are not written.
+func (m *Map[K, V]) Snapshot(w io.Writer) error {
+	c := m.codec()
+	bw := bufio.NewWriter(w)
+	var errs []error
+	now := time.Now()
+	for _, rec := range m.records(now) {
+		data, err := c.Marshal(rec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("lazy: encoding value of key %v: %w", rec.Key, err))
+			continue
+		}
+		if len(data) > maxSnapshotRecord {
+			errs = append(errs, fmt.Errorf("lazy: encoded value of key %v exceeds %d bytes", rec.Key, maxSnapshotRecord))
+			continue
+		}
+		if _, err := bw.Write(binary.AppendUvarint(nil, uint64(len(data)))); err != nil {
+			return err
+		}
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// records returns the values of m not expired at now, least recently used
+// first within each shard. The shards are locked together so that the values
+// form a consistent view of m.
+func (m *Map[K, V]) records(now time.Time) []snapshotRecord[K, V] {
+	for i := range m.shards {
+		m.shards[i].mu.Lock()
+	}
+	defer func() {
+		for i := range m.shards {
+			m.shards[i].mu.Unlock()
+		}
+	}()
+
+	var recs []snapshotRecord[K, V]
+	for i := range m.shards {
+		sh := &m.shards[i]
+		for elem := sh.lru.Back(); elem != nil; elem = elem.Prev() {
+			e := elem.Value.(*mapEntry[K, V])
+			s, ok := e.l.peek()
+			if !ok || s.expired(now) {
+				continue
+			}
+			rec := snapshotRecord[K, V]{Key: e.key, Value: s.value, Loaded: s.loaded, Tags: s.tags}
+			if !s.expires.IsZero() {
+				rec.TTL = s.expires.Sub(s.loaded)
+			}
+			recs = append(recs, rec)
+		}
+	}
+	return recs
+}
+
+// Restore reads values written by Snapshot from r and caches them in m, as if
+// they had been loaded when they originally were, so that they expire on
+// schedule. Values that have expired since and keys already in m are skipped.
+// Values that cannot be decoded are skipped too; Restore then restores the
+// others and returns an error describing the skipped ones.
+func (m *Map[K, V]) Restore(r io.Reader) error {
+	c := m.codec()
+	br := bufio.NewReader(r)
+	var errs []error
+	for {
+		n, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("lazy: reading snapshot: %w", noEOF(err))
+		}
+		if n > maxSnapshotRecord {
+			return fmt.Errorf("lazy: reading snapshot: record of %d bytes exceeds %d bytes", n, maxSnapshotRecord)
+		}
+		// Read through a LimitReader rather than into a buffer of n bytes,
+		// so that a truncated snapshot allocates no more than it holds.
+		data, err := io.ReadAll(io.LimitReader(br, int64(n)))
+		if err != nil {
+			return fmt.Errorf("lazy: reading snapshot: %w", err)
+		}
+		if uint64(len(data)) < n {
+			return fmt.Errorf("lazy: reading snapshot: %w", io.ErrUnexpectedEOF)
+		}
+
+		var rec snapshotRecord[K, V]
+		if err := c.Unmarshal(data, &rec); err != nil {
+			errs = append(errs, fmt.Errorf("lazy: decoding snapshot record: %w", err))
+			continue
+		}
+		s := stamped[V]{value: rec.Value, loaded: rec.Loaded, tags: rec.Tags}
+		if rec.TTL > 0 {
+			s.expires = rec.Loaded.Add(rec.TTL)
+		}
+		if !s.expired(time.Now()) {
+			m.restore(rec.Key, s)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// restore caches s as the value for key unless key is already in m.
+func (m *Map[K, V]) restore(key K, s stamped[V]) {
+	h := m.hasher.Hash(key)
+	sh := m.shard(h)
+
+	sh.mu.Lock()
+	defer m.unlock(sh)
+	if m.findLocked(sh, key, h) != nil {
+		return
+	}
+	e := m.insertLocked(sh, key, h)
+	e.l.Set(s)
+	m.cachedLocked(sh, e, s.value)
+	if m.mo.Cost != nil {
+		e.cost = m.mo.Cost(s.value)
+		sh.cost += e.cost
+	}
+	sh.evictLocked(nil)
+}
+
+// codec returns the Codec set with WithCodec, or the default.
+func (m *Map[K, V]) codec() Codec {
+	if c := m.opts.codec; c != nil {
+		return c
+	}
+	return GobCodec{}
+}
+
+// noEOF converts io.EOF, which means a truncated snapshot in the middle of a
+// record, to io.ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package lazy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestMap_SnapshotRestore(t *testing.T) {
+	for _, c := range []Codec{GobCodec{}, JSONCodec{}} {
+		synctest.Test(t, func(t *testing.T) {
+			src := NewMap(func(ctx context.Context, key string) (string, error) {
+				if key == "forever" {
+					SetTTL(ctx, 0)
+				}
+				return "value of " + key, nil
+			}, WithTTL(time.Minute), WithCodec(c))
+
+			ctx := context.Background()
+			src.Get(ctx, "old")
+			time.Sleep(30 * time.Second)
+			src.Get(ctx, "new")
+			src.Get(ctx, "forever")
+
+			var buf bytes.Buffer
+			if err := src.Snapshot(&buf); err != nil {
+				t.Fatalf("%T: Snapshot: %v", c, err)
+			}
+
+			var calls atomic.Int32
+			dst := NewMap(func(ctx context.Context, key string) (string, error) {
+				calls.Add(1)
+				return "reloaded " + key, nil
+			}, WithTTL(time.Minute), WithCodec(c))
+			time.Sleep(45 * time.Second)
+			if err := dst.Restore(&buf); err != nil {
+				t.Fatalf("%T: Restore: %v", c, err)
+			}
+
+			// "old" expired before it was restored.
+			want := map[string]string{
+				"old":     "reloaded old",
+				"new":     "value of new",
+				"forever": "value of forever",
+			}
+			for key, value := range want {
+				if v, _ := dst.Get(ctx, key); v != value {
+					t.Fatalf("%T: got %q for %s, want %q", c, v, key, value)
+				}
+			}
+			if got := calls.Load(); got != 1 {
+				t.Fatalf("%T: load called %d times, want 1", c, got)
+			}
+
+			// Restored values keep their original expiry.
+			time.Sleep(15 * time.Second)
+			if v, _ := dst.Get(ctx, "new"); v != "reloaded new" {
+				t.Fatalf("%T: got %q after expiry, want %q", c, v, "reloaded new")
+			}
+		})
+	}
+}
+
+func TestMap_SnapshotSkipsUnencodable(t *testing.T) {
+	src := NewMap(func(ctx context.Context, key string) (any, error) {
+		if key == "func" {
+			return func() {}, nil
+		}
+		return key, nil
+	}, WithCodec(JSONCodec{}))
+	ctx := context.Background()
+	src.Get(ctx, "a")
+	src.Get(ctx, "func")
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err == nil {
+		t.Fatal("Snapshot succeeded with a value that cannot be encoded")
+	}
+
+	dst := NewMap(func(ctx context.Context, key string) (any, error) {
+		return nil, errors.New("not restored")
+	}, WithCodec(JSONCodec{}))
+	if err := dst.Restore(&buf); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if v, err := dst.Get(ctx, "a"); err != nil || v != "a" {
+		t.Fatalf("got (%v, %v), want (a, nil)", v, err)
+	}
+}
+
+func TestMap_RestoreTruncated(t *testing.T) {
+	src := NewMap(func(ctx context.Context, key string) (string, error) {
+		return key, nil
+	})
+	src.Get(context.Background(), "a")
+	var buf bytes.Buffer
+	src.Snapshot(&buf)
+
+	dst := NewMap(func(ctx context.Context, key string) (string, error) {
+		return key, nil
+	})
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-1])
+	if err := dst.Restore(truncated); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestMap_RestoreCorrupt(t *testing.T) {
+	m := NewMap(func(ctx context.Context, key string) (string, error) {
+		return key, nil
+	})
+	for _, n := range []uint64{1 << 62, maxSnapshotRecord + 1, 1 << 20} {
+		data := binary.AppendUvarint(nil, n)
+		data = append(data, "short"...)
+		if err := m.Restore(bytes.NewReader(data)); err == nil {
+			t.Fatalf("got no error for a record of %d bytes holding 5", n)
+		}
+	}
+	if m.Len() != 0 {
+		t.Fatalf("got %d values restored from corrupt snapshots, want 0", m.Len())
+	}
+}
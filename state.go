@@ -0,0 +1,116 @@
+package lazy
+
+import (
+	"context"
+	"fmt"
+)
+
+// A State describes the initialization status of a Lazy.
+type State int
+
+const (
+	// StateIdle means the value has not been initialized and no
+	// initialization is in progress.
+	StateIdle State = iota
+
+	// StateLoading means an initialization is in progress.
+	StateLoading
+
+	// StateReady means the value is cached.
+	StateReady
+
+	// StateFailed means the most recent initialization failed and no other
+	// is in progress.
+	StateFailed
+
+	// StateClosed means the Lazy has been closed with Close.
+	StateClosed
+
+	// StateDegraded means the initialization failed and Get serves the
+	// fallback value of a Lazy returned by Fallback instead.
+	StateDegraded
+)
+
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateLoading:
+		return "loading"
+	case StateReady:
+		return "ready"
+	case StateFailed:
+		return "failed"
+	case StateClosed:
+		return "closed"
+	case StateDegraded:
+		return "degraded"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// State returns the initialization status of l.
+func (l *Lazy[T]) State() State {
+	switch {
+	case l.value.Load() != nil:
+		return StateReady
+	case l.closed.Load():
+		return StateClosed
+	case l.busy():
+		return StateLoading
+	case l.degraded.Load():
+		return StateDegraded
+	case l.failure.Load() != nil:
+		return StateFailed
+	}
+	return StateIdle
+}
+
+// Prime initializes l if it has not been initialized yet, discarding the
+// value. It is useful to warm up a Lazy ahead of its first use.
+func (l *Lazy[T]) Prime(ctx context.Context) error {
+	_, err := l.Get(ctx)
+	return err
+}
+
+// Invalidate discards the cached value of l, if any, so that the next Get
+// calls the initialization function again. An initialization in progress is
+// not affected and caches its result when it finishes. The cleanup function of
+// the discarded value, if any, is called with context.Background and its error
+// is ignored; use Close to observe it.
+func (l *Lazy[T]) Invalidate() {
+	cleanup, _ := l.swap(nil)
+	runCleanup(cleanup)
+}
+
+// State returns StateLoading if some values of m are being loaded, StateFailed
+// if m is not ready according to Check, and otherwise StateReady, since a Map
+// loads its values on demand. A Map is thus a Handle that can be registered.
+func (m *Map[K, V]) State() State {
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.Lock()
+		for elem := sh.lru.Front(); elem != nil; elem = elem.Next() {
+			if elem.Value.(*mapEntry[K, V]).l.busy() {
+				sh.mu.Unlock()
+				return StateLoading
+			}
+		}
+		sh.mu.Unlock()
+	}
+	if m.Check() != nil {
+		return StateFailed
+	}
+	return StateReady
+}
+
+// Prime does nothing and returns nil, since a Map loads its values on demand.
+// It is part of the Handle interface.
+func (m *Map[K, V]) Prime(ctx context.Context) error {
+	return nil
+}
+
+// Invalidate is like ForgetAll. It is part of the Handle interface.
+func (m *Map[K, V]) Invalidate() {
+	m.ForgetAll()
+}
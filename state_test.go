@@ -0,0 +1,54 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+)
+
+func TestLazy_State(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		proceed := make(chan error)
+		l := New(func(ctx context.Context) (int, error) {
+			return 1, <-proceed
+		})
+
+		check := func(want State) {
+			t.Helper()
+			synctest.Wait()
+			if got := l.State(); got != want {
+				t.Fatalf("got state %v, want %v", got, want)
+			}
+		}
+
+		check(StateIdle)
+		go l.Get(context.Background())
+		check(StateLoading)
+		proceed <- errors.New("failed")
+		check(StateFailed)
+		go l.Get(context.Background())
+		check(StateLoading)
+		proceed <- nil
+		check(StateReady)
+	})
+}
+
+func TestLazy_Invalidate(t *testing.T) {
+	var calls atomic.Int32
+	l := New(func(ctx context.Context) (int32, error) {
+		return calls.Add(1), nil
+	})
+
+	if err := l.Prime(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	l.Invalidate()
+	if l.State() != StateIdle {
+		t.Fatalf("got state %v after Invalidate, want %v", l.State(), StateIdle)
+	}
+	if v, _ := l.Get(context.Background()); v != 2 {
+		t.Fatalf("got %d after Invalidate, want 2", v)
+	}
+}
@@ -0,0 +1,82 @@
+package lazy
+
+import "sync/atomic"
+
+// Stats are counters describing the initializations of a Lazy, or of all the
+// keys of a Map. They only cover callers that found no cached value, so that
+// reading a cached value stays free of shared writes.
+type Stats struct {
+	// Loads is the number of calls of the initialization function.
+	Loads uint64
+
+	// LoadErrors is the number of those calls that failed.
+	LoadErrors uint64
+
+	// Deduplicated is the number of callers that waited for the load of
+	// another caller and shared its outcome instead of loading themselves.
+	Deduplicated uint64
+}
+
+// MapStats are counters describing the use of a Map.
+type MapStats struct {
+	Stats
+
+	// Hits is the number of Get calls served a cached value, including
+	// stale values served while they are revalidated.
+	Hits uint64
+
+	// Misses is the number of Get calls that found no servable value and
+	// waited for a load.
+	Misses uint64
+
+	// Evictions is the number of values evicted to stay within the limits
+	// set by WithCapacity or MapOptions.MaxCost.
+	Evictions uint64
+}
+
+// lazyCounters hold the counters reported as Stats.
+type lazyCounters struct {
+	loads        atomic.Uint64
+	loadErrors   atomic.Uint64
+	deduplicated atomic.Uint64
+	loadTimes    histogramCounters
+	waitTimes    histogramCounters
+}
+
+func (c *lazyCounters) stats() Stats {
+	return Stats{
+		Loads:        c.loads.Load(),
+		LoadErrors:   c.loadErrors.Load(),
+		Deduplicated: c.deduplicated.Load(),
+	}
+}
+
+// mapCounters hold the counters of a Map shard reported as MapStats. The Lazy
+// of each entry of the shard counts its initializations in lazy.
+type mapCounters struct {
+	lazy      lazyCounters
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// Stats returns the counters of l.
+func (l *Lazy[T]) Stats() Stats {
+	return l.stats.stats()
+}
+
+// Stats returns the counters of m, summed over its shards.
+func (m *Map[K, V]) Stats() MapStats {
+	var st MapStats
+	for i := range m.shards {
+		c := &m.shards[i].stats
+		s := c.lazy.stats()
+		st.Loads += s.Loads
+		st.LoadErrors += s.LoadErrors
+		st.Deduplicated += s.Deduplicated
+		st.Hits += c.hits.Load()
+		st.Misses += c.misses.Load()
+		st.Evictions += c.evictions.Load()
+	}
+	return st
+}
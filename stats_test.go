@@ -0,0 +1,75 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestLazy_Stats(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fail := true
+		l := New(func(ctx context.Context) (int, error) {
+			time.Sleep(time.Second)
+			if fail {
+				fail = false
+				return 0, errors.New("failed")
+			}
+			return 1, nil
+		}, WithSharedResults())
+
+		for range 3 {
+			go l.Get(context.Background())
+		}
+		synctest.Wait()
+		time.Sleep(time.Second)
+		synctest.Wait()
+		// Retry.
+		l.Get(context.Background())
+		l.Get(context.Background())
+
+		want := Stats{Loads: 2, LoadErrors: 1, Deduplicated: 2}
+		if got := l.Stats(); got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestMap_Stats(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		m := NewMap(func(ctx context.Context, key int) (int, error) {
+			time.Sleep(time.Second)
+			if key < 0 {
+				return 0, errors.New("negative key")
+			}
+			return key, nil
+		}, WithCapacity(2), WithTTL(time.Minute))
+
+		ctx := context.Background()
+		for range 2 {
+			go m.Get(ctx, 0)
+		}
+		synctest.Wait()
+		time.Sleep(time.Second)
+		synctest.Wait()
+		m.Get(ctx, 0)
+		m.Get(ctx, -1) // Failed keys take no room.
+		m.Get(ctx, 1)
+		m.Get(ctx, 0)
+		m.Get(ctx, 3) // Evicts 1.
+		time.Sleep(time.Minute)
+		m.Get(ctx, 0) // Reloads the expired value.
+
+		want := MapStats{
+			Stats:     Stats{Loads: 5, LoadErrors: 1, Deduplicated: 1},
+			Hits:      2,
+			Misses:    6,
+			Evictions: 1,
+		}
+		if got := m.Stats(); got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	})
+}
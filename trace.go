@@ -0,0 +1,39 @@
+package lazy
+
+import (
+	"context"
+	"reflect"
+	"runtime/trace"
+	"strconv"
+)
+
+// traceName returns the name of l in execution traces: its name set with
+// WithName, or else the type of its value.
+func (l *Lazy[T]) traceName() string {
+	if l.opts.name != "" {
+		return l.opts.name
+	}
+	return reflect.TypeFor[T]().String()
+}
+
+// traceTask starts a task of the execution trace being recorded, if any, for
+// the attempt numbered n to initialize l. It returns the context to run the
+// attempt with and a function that ends the task.
+func (l *Lazy[T]) traceTask(ctx context.Context, n int) (context.Context, func()) {
+	if !trace.IsEnabled() {
+		return ctx, func() {}
+	}
+	ctx, task := trace.NewTask(ctx, "lazy.init "+l.traceName())
+	trace.Log(ctx, "lazy.attempt", strconv.Itoa(n))
+	return ctx, task.End
+}
+
+// traceWait starts a region of the execution trace being recorded, if any, for
+// a caller waiting for the initialization of l. It returns a function that
+// ends the region.
+func (l *Lazy[T]) traceWait(ctx context.Context) func() {
+	if !trace.IsEnabled() {
+		return func() {}
+	}
+	return trace.StartRegion(ctx, "lazy.wait "+l.traceName()).End
+}
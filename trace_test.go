@@ -0,0 +1,41 @@
+package lazy
+
+import (
+	"bytes"
+	"context"
+	"runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecutionTrace(t *testing.T) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skipf("cannot record an execution trace: %v", err)
+	}
+	proceed := make(chan struct{})
+	l := New(func(ctx context.Context) (int, error) {
+		<-proceed
+		return 1, nil
+	}, WithName("traced"))
+
+	var wg sync.WaitGroup
+	wg.Go(func() { l.Get(context.Background()) })
+	for !l.busy() {
+		time.Sleep(time.Millisecond)
+	}
+	wg.Go(func() { l.Get(context.Background()) })
+	for l.sem.waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(proceed)
+	wg.Wait()
+	trace.Stop()
+
+	for _, name := range []string{"lazy.init traced", "lazy.wait traced"} {
+		if !bytes.Contains(buf.Bytes(), []byte(name)) {
+			t.Errorf("execution trace does not mention %q", name)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package lazy
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// stamped is a cached Map value along with its expiry.
+type stamped[V any] struct {
+	value   V
+	loaded  time.Time     // when the load finished
+	expires time.Time     // zero if the value does not expire
+	delta   time.Duration // how long the load took
+	tags    []string      // set by the loader with Tag
+
+	// cleanup releases value, if it was loaded by NewMapWithCleanup.
+	cleanup func(context.Context) error
+}
+
+// expired reports whether s has expired at now.
+func (s stamped[V]) expired(now time.Time) bool {
+	return !s.expires.IsZero() && !now.Before(s.expires)
+}
+
+// refreshEarly reports whether s should be refreshed at now, before it
+// expires, according to the XFetch algorithm: the probability of a refresh
+// grows as the expiry approaches, and faster for values that are slow to load,
+// so that a single reader usually refreshes a hot key before it expires.
+func (s stamped[V]) refreshEarly(now time.Time, beta float64) bool {
+	if beta <= 0 || s.expires.IsZero() {
+		return false
+	}
+	gap := -float64(s.delta) * beta * math.Log(1-rand.Float64())
+	return gap >= float64(s.expires.Sub(now))
+}
+
+// loadState collects the settings made by a loader through its context.
+type loadState struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	expires time.Time // set by SetExpiry, taking precedence over ttl
+	tags    []string
+	cleanup func(context.Context) error
+}
+
+type loadStateKey struct{}
+
+// expiry returns the time at which the value v, loaded at now, expires, or the
+// zero time if it does not expire.
+func (state *loadState) expiry(now time.Time, v any) time.Time {
+	if x, ok := v.(Expirable); ok {
+		if t := x.ExpiresAt(); !t.IsZero() {
+			return t
+		}
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	switch {
+	case !state.expires.IsZero():
+		return state.expires
+	case state.ttl > 0:
+		return now.Add(state.ttl)
+	}
+	return time.Time{}
+}
+
+// loadStamped calls load with a context through which load can adjust the
+// settings of its result, and stamps a successful result with its expiry.
+func loadStamped[V any](ctx context.Context, ttl time.Duration, load func(context.Context) (V, error)) (stamped[V], error) {
+	state := &loadState{ttl: ttl}
+	start := time.Now()
+	v, err := load(context.WithValue(ctx, loadStateKey{}, state))
+	if err != nil {
+		return stamped[V]{}, err
+	}
+
+	now := time.Now()
+	s := stamped[V]{value: v, loaded: now, delta: now.Sub(start)}
+	s.expires = state.expiry(now, v)
+	state.mu.Lock()
+	s.tags = state.tags
+	s.cleanup = state.cleanup
+	state.mu.Unlock()
+	return s, nil
+}
+
+// loadTTL returns the remaining time to live of v, being loaded with ctx, or
+// zero if it does not expire.
+func loadTTL(ctx context.Context, v any) time.Duration {
+	state, ok := ctx.Value(loadStateKey{}).(*loadState)
+	if !ok {
+		return 0
+	}
+	now := time.Now()
+	if t := state.expiry(now, v); !t.IsZero() {
+		return max(t.Sub(now), time.Nanosecond)
+	}
+	return 0
+}
+
+// An Expirable value knows when it expires, such as a token with an expiry
+// timestamp or a DNS record with a TTL. A Map caching an Expirable value
+// expires it at the time reported by ExpiresAt, taking precedence over WithTTL,
+// SetTTL and SetExpiry. A zero time means the value does not determine its
+// expiry.
+type Expirable interface {
+	ExpiresAt() time.Time
+}
+
+// SetExpiry sets the time at which the value being loaded by a Map expires,
+// overriding the duration configured with WithTTL or set by an earlier SetTTL.
+// It must be called with the context passed to the Map's load function, or one
+// derived from it; otherwise it has no effect.
+func SetExpiry(ctx context.Context, t time.Time) {
+	state, ok := ctx.Value(loadStateKey{}).(*loadState)
+	if !ok {
+		return
+	}
+	state.mu.Lock()
+	state.expires = t
+	state.mu.Unlock()
+}
+
+// SetTTL sets the time to live of the value being loaded by a Map, overriding
+// the duration configured with WithTTL or set by an earlier SetExpiry. It must
+// be called with the context passed to the Map's load function, or one derived
+// from it; otherwise it has no effect. A duration of zero or less means the
+// value does not expire.
+func SetTTL(ctx context.Context, d time.Duration) {
+	state, ok := ctx.Value(loadStateKey{}).(*loadState)
+	if !ok {
+		return
+	}
+	state.mu.Lock()
+	state.ttl = d
+	state.expires = time.Time{}
+	state.mu.Unlock()
+}
+
+// Tag attaches tags to the value being loaded by a Map, so that it can be
+// removed along with all other values carrying one of them by InvalidateTag.
+// It must be called with the context passed to the Map's load function, or one
+// derived from it; otherwise it has no effect.
+func Tag(ctx context.Context, tags ...string) {
+	state, ok := ctx.Value(loadStateKey{}).(*loadState)
+	if !ok {
+		return
+	}
+	state.mu.Lock()
+	state.tags = append(state.tags, tags...)
+	state.mu.Unlock()
+}
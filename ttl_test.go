@@ -0,0 +1,377 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestWithTTL(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+
+		m := NewMap(func(ctx context.Context, key string) (int32, error) {
+			return calls.Add(1), nil
+		}, WithTTL(time.Minute))
+
+		ctx := context.Background()
+		if v, _ := m.Get(ctx, "a"); v != 1 {
+			t.Fatalf("got %d, want 1", v)
+		}
+		time.Sleep(time.Minute - time.Nanosecond)
+		if v, _ := m.Get(ctx, "a"); v != 1 {
+			t.Fatalf("got %d before expiry, want 1", v)
+		}
+		time.Sleep(time.Nanosecond)
+		if v, _ := m.Get(ctx, "a"); v != 2 {
+			t.Fatalf("got %d after expiry, want 2", v)
+		}
+	})
+}
+
+func TestWithTTL_SingleReload(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		proceed := make(chan struct{})
+
+		m := NewMap(func(ctx context.Context, key string) (int32, error) {
+			n := calls.Add(1)
+			if n > 1 {
+				<-proceed
+			}
+			return n, nil
+		}, WithTTL(time.Minute))
+
+		m.Get(context.Background(), "a")
+		time.Sleep(time.Minute)
+
+		results := make(chan int32, 3)
+		for range 3 {
+			go func() {
+				v, _ := m.Get(context.Background(), "a")
+				results <- v
+			}()
+		}
+		synctest.Wait()
+		close(proceed)
+
+		for range 3 {
+			if v := <-results; v != 2 {
+				t.Fatalf("got %d, want 2", v)
+			}
+		}
+		if got := calls.Load(); got != 2 {
+			t.Fatalf("load called %d times, want 2", got)
+		}
+	})
+}
+
+func TestSetTTL(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+
+		m := NewMap(func(ctx context.Context, key string) (int32, error) {
+			if key == "short" {
+				SetTTL(ctx, time.Second)
+			}
+			return calls.Add(1), nil
+		}, WithTTL(time.Hour))
+
+		ctx := context.Background()
+		m.Get(ctx, "short")
+		m.Get(ctx, "long")
+		time.Sleep(time.Second)
+
+		m.Get(ctx, "short")
+		m.Get(ctx, "long")
+		if got := calls.Load(); got != 3 {
+			t.Fatalf("load called %d times, want 3", got)
+		}
+	})
+}
+
+func TestWithStaleWhileRevalidate(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		proceed := make(chan struct{})
+
+		m := NewMap(func(ctx context.Context, key string) (int32, error) {
+			n := calls.Add(1)
+			if n > 1 {
+				<-proceed
+			}
+			return n, nil
+		}, WithTTL(time.Minute), WithStaleWhileRevalidate(time.Minute))
+
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		time.Sleep(time.Minute)
+
+		// Readers get the stale value without blocking while one background
+		// load refreshes it.
+		for range 3 {
+			if v, err := m.Get(ctx, "a"); err != nil || v != 1 {
+				t.Fatalf("got (%d, %v), want stale (1, nil)", v, err)
+			}
+		}
+		synctest.Wait()
+		if got := calls.Load(); got != 2 {
+			t.Fatalf("load called %d times, want 2", got)
+		}
+
+		close(proceed)
+		synctest.Wait()
+		if v, _ := m.Get(ctx, "a"); v != 2 {
+			t.Fatalf("got %d after revalidation, want 2", v)
+		}
+	})
+}
+
+func TestWithStaleWhileRevalidate_WindowExpired(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+
+		m := NewMap(func(ctx context.Context, key string) (int32, error) {
+			return calls.Add(1), nil
+		}, WithTTL(time.Minute), WithStaleWhileRevalidate(time.Second))
+
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		time.Sleep(time.Minute + time.Second)
+
+		// Past the stale window, readers wait for the reload.
+		if v, _ := m.Get(ctx, "a"); v != 2 {
+			t.Fatalf("got %d, want 2", v)
+		}
+	})
+}
+
+func TestWithJanitor(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var (
+			mu      sync.Mutex
+			evicted []string
+		)
+		m := NewMapWithOptions(func(ctx context.Context, key string) (string, error) {
+			return key, nil
+		}, MapOptions[string, string]{
+			OnEvict: func(key, value string, reason EvictReason) {
+				if reason != EvictExpired {
+					t.Errorf("got reason %v, want %v", reason, EvictExpired)
+				}
+				mu.Lock()
+				evicted = append(evicted, key)
+				mu.Unlock()
+			},
+		}, WithTTL(time.Minute), WithJanitor(time.Second))
+		defer m.Close()
+		snapshot := func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return slices.Clone(evicted)
+		}
+
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		time.Sleep(30 * time.Second)
+		m.Get(ctx, "b")
+
+		time.Sleep(30*time.Second + time.Millisecond)
+		synctest.Wait()
+		if got := snapshot(); !slices.Equal(got, []string{"a"}) {
+			t.Fatalf("got evicted %q after the first expiry, want [a]", got)
+		}
+		time.Sleep(30 * time.Second)
+		synctest.Wait()
+		if got := snapshot(); !slices.Equal(got, []string{"a", "b"}) {
+			t.Fatalf("got evicted %q after the second expiry, want [a b]", got)
+		}
+	})
+}
+
+func TestMap_Close(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		m := NewMap(func(ctx context.Context, key string) (string, error) {
+			return key, nil
+		}, WithTTL(time.Minute), WithJanitor(time.Second))
+		m.Close()
+		m.Close()
+		// synctest.Test fails if the janitor goroutine is still running.
+	})
+}
+
+func TestWithEarlyExpiration(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+
+		m := NewMap(func(ctx context.Context, key string) (int32, error) {
+			time.Sleep(time.Second)
+			return calls.Add(1), nil
+		}, WithTTL(1000*time.Hour), WithEarlyExpiration(1))
+
+		// With a load time this small relative to the TTL, an early refresh is
+		// all but impossible right after the load and all but certain just
+		// before the expiry. The current value is served while it runs.
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		time.Sleep(1000*time.Hour - time.Nanosecond)
+		if v, _ := m.Get(ctx, "a"); v != 1 {
+			t.Fatalf("got %d during the early refresh, want 1", v)
+		}
+		if v, _ := m.Get(ctx, "a"); v != 1 {
+			t.Fatalf("got %d during the early refresh, want 1", v)
+		}
+		time.Sleep(time.Second)
+		synctest.Wait()
+		if got := calls.Load(); got != 2 {
+			t.Fatalf("load called %d times, want 2", got)
+		}
+		if v, _ := m.Get(ctx, "a"); v != 2 {
+			t.Fatalf("got %d after the early refresh, want 2", v)
+		}
+	})
+}
+
+func TestWithEarlyExpiration_FastLoads(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+
+		m := NewMap(func(ctx context.Context, key string) (int32, error) {
+			return calls.Add(1), nil
+		}, WithTTL(time.Minute), WithEarlyExpiration(1))
+
+		// A value that loads instantly is never refreshed early.
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		time.Sleep(time.Minute - time.Nanosecond)
+		m.Get(ctx, "a")
+		synctest.Wait()
+		if got := calls.Load(); got != 1 {
+			t.Fatalf("load called %d times, want 1", got)
+		}
+	})
+}
+
+func TestWithRefreshAhead(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		m := NewMap(func(ctx context.Context, key string) (int32, error) {
+			time.Sleep(time.Second)
+			return calls.Add(1), nil
+		}, WithTTL(time.Minute), WithRefreshAhead(5*time.Second, 2, 1))
+
+		ctx := context.Background()
+		m.Get(ctx, "hot")
+		m.Get(ctx, "cold")
+		m.Get(ctx, "hot")
+		m.Get(ctx, "hot") // Makes "hot" hot.
+		m.Get(ctx, "cold")
+
+		// The refresh starts 5s before the expiry and takes 1s.
+		time.Sleep(time.Minute - 3*time.Second)
+		synctest.Wait()
+		if got := calls.Load(); got != 3 {
+			t.Fatalf("load called %d times, want 3", got)
+		}
+
+		// Readers of the hot key get the refreshed value without waiting,
+		// while the cold key expires as usual.
+		time.Sleep(3 * time.Second)
+		start := time.Now()
+		if v, _ := m.Get(ctx, "hot"); v != 3 {
+			t.Fatalf("got %d for the hot key, want 3", v)
+		}
+		if d := time.Since(start); d != 0 {
+			t.Fatalf("Get of the hot key took %v, want 0", d)
+		}
+		if v, _ := m.Get(ctx, "cold"); v != 4 {
+			t.Fatalf("got %d for the cold key, want 4", v)
+		}
+	})
+}
+
+func TestWithRefreshAhead_Concurrency(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		m := NewMap(func(ctx context.Context, key int) (int32, error) {
+			time.Sleep(time.Second)
+			return calls.Add(1), nil
+		}, WithTTL(time.Minute), WithRefreshAhead(5*time.Second, 1, 1))
+
+		ctx := context.Background()
+		keys := []int{0, 1, 2}
+		m.GetMany(ctx, keys)
+		m.GetMany(ctx, keys) // Makes all keys hot.
+		time.Sleep(58 * time.Second)
+		synctest.Wait()
+
+		// Keys loaded together are due for a refresh together, so only one
+		// of them is refreshed ahead.
+		if got := calls.Load(); got != 4 {
+			t.Fatalf("load called %d times, want 4", got)
+		}
+	})
+}
+
+type token struct {
+	id      int32
+	expires time.Time
+}
+
+func (t token) ExpiresAt() time.Time { return t.expires }
+
+func TestExpirable(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		m := NewMap(func(ctx context.Context, key string) (token, error) {
+			return token{calls.Add(1), time.Now().Add(time.Second)}, nil
+		}, WithTTL(time.Minute))
+
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		time.Sleep(time.Second)
+		if v, _ := m.Get(ctx, "a"); v.id != 2 {
+			t.Fatalf("got token %d after its expiry, want 2", v.id)
+		}
+	})
+}
+
+func TestSetExpiry(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var calls atomic.Int32
+		m := NewMap(func(ctx context.Context, key string) (int32, error) {
+			SetExpiry(ctx, time.Now().Add(time.Second))
+			return calls.Add(1), nil
+		}, WithTTL(time.Minute))
+
+		ctx := context.Background()
+		m.Get(ctx, "a")
+		time.Sleep(time.Second - time.Nanosecond)
+		if v, _ := m.Get(ctx, "a"); v != 1 {
+			t.Fatalf("got %d before expiry, want 1", v)
+		}
+		time.Sleep(time.Nanosecond)
+		if v, _ := m.Get(ctx, "a"); v != 2 {
+			t.Fatalf("got %d after expiry, want 2", v)
+		}
+	})
+}
+
+func TestMap_CloseTerminal(t *testing.T) {
+	m := NewMap(func(ctx context.Context, key string) (string, error) {
+		return key, nil
+	})
+	m.Get(context.Background(), "a")
+	m.Close()
+	if _, err := m.Get(context.Background(), "a"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error %v after Close, want %v", err, ErrClosed)
+	}
+	if r := m.GetMany(context.Background(), []string{"a"}); !errors.Is(r[0].Err, ErrClosed) {
+		t.Fatalf("got error %v from GetMany after Close, want %v", r[0].Err, ErrClosed)
+	}
+}
@@ -0,0 +1,143 @@
+package lazy
+
+import (
+	"context"
+	"slices"
+)
+
+// Watch returns a channel that receives each value cached by l from now on,
+// whether returned by the initialization function or set with Set, so that
+// state derived from the value can be rebuilt when it changes. The value cached
+// when Watch is called, if any, is not sent.
+//
+// The channel has a buffer of one value and the latest value wins: if the
+// receiver falls behind, the value waiting in the buffer is replaced by the
+// newer one, so that a slow receiver skips intermediate values but always
+// receives the latest. The channel is closed once ctx is done or l is closed.
+func (l *Lazy[T]) Watch(ctx context.Context) <-chan T {
+	c := make(chan T, 1)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed.Load() {
+		close(c)
+		return c
+	}
+	l.watchers = append(l.watchers, c)
+	context.AfterFunc(ctx, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if i := slices.Index(l.watchers, c); i >= 0 {
+			l.watchers = slices.Delete(l.watchers, i, i+1)
+			close(c)
+		}
+	})
+	return c
+}
+
+// cachedLocked records that p, if not nil, has just been cached: it advances
+// the generation of l and sends the value to its watchers, replacing any value
+// they have not received yet. It must be called with l.mu held.
+func (l *Lazy[T]) cachedLocked(p *T) {
+	if p == nil {
+		return
+	}
+	l.generation.Add(1)
+	l.degraded.Store(false)
+	for _, c := range l.watchers {
+		select {
+		case <-c:
+		default:
+		}
+		c <- *p
+	}
+}
+
+// Generation returns the number of values cached by l so far, whether returned
+// by the initialization function or set with Set. It changes whenever a new
+// value is cached, so that state derived from the value can be rebuilt only when
+// its generation is out of date, without comparing values. It is zero if l has
+// never cached a value.
+func (l *Lazy[T]) Generation() uint64 {
+	return l.generation.Load()
+}
+
+// closeWatchersLocked closes the channels returned by Watch. It must be called
+// with l.mu held.
+func (l *Lazy[T]) closeWatchersLocked() {
+	for _, c := range l.watchers {
+		close(c)
+	}
+	l.watchers = nil
+}
+
+// A mapWatcher is a channel returned by Map.Watch for key.
+type mapWatcher[K, V any] struct {
+	key K
+	c   chan V
+}
+
+// Watch returns a channel that receives each value cached by m for key from
+// now on, whether loaded by Get, reloaded once expired or refreshed ahead with
+// WithRefreshAhead, or restored with Restore, so that state derived from the
+// value can be rebuilt when it changes. The value cached when Watch is called,
+// if any, is not sent. Key need not be in m: the channel keeps receiving the
+// values of key across its removals and reloads.
+//
+// The channel has a buffer of one value and the latest value wins, as with the
+// Watch method of Lazy. The channel is closed once ctx is done or m is closed.
+func (m *Map[K, V]) Watch(ctx context.Context, key K) <-chan V {
+	c := make(chan V, 1)
+	h := m.hasher.Hash(key)
+	sh := m.shard(h)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if m.closed.Load() {
+		close(c)
+		return c
+	}
+	if sh.watchers == nil {
+		sh.watchers = make(map[uint64][]mapWatcher[K, V])
+	}
+	sh.watchers[h] = append(sh.watchers[h], mapWatcher[K, V]{key, c})
+	context.AfterFunc(ctx, func() {
+		sh.mu.Lock()
+		defer sh.mu.Unlock()
+		ws := sh.watchers[h]
+		if i := slices.IndexFunc(ws, func(w mapWatcher[K, V]) bool { return w.c == c }); i >= 0 {
+			if ws = slices.Delete(ws, i, i+1); len(ws) == 0 {
+				delete(sh.watchers, h)
+			} else {
+				sh.watchers[h] = ws
+			}
+			close(c)
+		}
+	})
+	return c
+}
+
+// cachedLocked sends v, just cached for e, to the watchers of its key,
+// replacing any value they have not received yet. It must be called with sh.mu
+// held, sh being the shard of e.
+func (m *Map[K, V]) cachedLocked(sh *mapShard[K, V], e *mapEntry[K, V], v V) {
+	for _, w := range sh.watchers[e.hash] {
+		if !m.hasher.Equal(w.key, e.key) {
+			continue
+		}
+		select {
+		case <-w.c:
+		default:
+		}
+		w.c <- v
+	}
+}
+
+// closeWatchersLocked closes the channels returned by Watch for the keys of
+// sh. It must be called with sh.mu held.
+func (sh *mapShard[K, V]) closeWatchersLocked() {
+	for _, ws := range sh.watchers {
+		for _, w := range ws {
+			close(w.c)
+		}
+	}
+	sh.watchers = nil
+}
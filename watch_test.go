@@ -0,0 +1,135 @@
+package lazy
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestLazy_Watch(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		n := 0
+		l := New(func(ctx context.Context) (int, error) {
+			n++
+			return n, nil
+		})
+		l.Prime(context.Background())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		c := l.Watch(ctx)
+		select {
+		case v := <-c:
+			t.Fatalf("got %d before any change, want nothing", v)
+		default:
+		}
+
+		l.Invalidate()
+		l.Prime(context.Background())
+		if v := <-c; v != 2 {
+			t.Fatalf("got %d, want 2", v)
+		}
+
+		// A slow receiver only gets the latest value.
+		l.Set(10)
+		l.Set(20)
+		if v := <-c; v != 20 {
+			t.Fatalf("got %d, want 20", v)
+		}
+
+		cancel()
+		synctest.Wait()
+		if _, ok := <-c; ok {
+			t.Fatal("channel not closed after the context is done")
+		}
+	})
+}
+
+func TestLazy_WatchClose(t *testing.T) {
+	l := New(func(ctx context.Context) (int, error) { return 1, nil })
+	c := l.Watch(context.Background())
+	l.Close(context.Background())
+	if _, ok := <-c; ok {
+		t.Fatal("channel not closed by Close")
+	}
+	if _, ok := <-l.Watch(context.Background()); ok {
+		t.Fatal("channel of a closed Lazy not closed")
+	}
+}
+
+func TestLazy_Generation(t *testing.T) {
+	l := New(func(ctx context.Context) (int, error) { return 1, nil })
+	if g := l.Generation(); g != 0 {
+		t.Fatalf("got generation %d before Get, want 0", g)
+	}
+	l.Get(context.Background())
+	l.Get(context.Background())
+	if g := l.Generation(); g != 1 {
+		t.Fatalf("got generation %d after Get, want 1", g)
+	}
+	l.Invalidate()
+	if g := l.Generation(); g != 1 {
+		t.Fatalf("got generation %d after Invalidate, want 1", g)
+	}
+	l.Set(2)
+	l.Get(context.Background())
+	if g := l.Generation(); g != 2 {
+		t.Fatalf("got generation %d after Set, want 2", g)
+	}
+}
+
+func TestMap_Watch(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		n := 0
+		m := NewMap(func(ctx context.Context, key string) (int, error) {
+			n++
+			return n, nil
+		}, WithTTL(time.Minute))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		c := m.Watch(ctx, "a")
+		m.Get(context.Background(), "a")
+		if v := <-c; v != 1 {
+			t.Fatalf("got %d, want 1", v)
+		}
+
+		// Values of other keys are not sent.
+		m.Get(context.Background(), "b")
+		select {
+		case v := <-c:
+			t.Fatalf("got %d for another key, want nothing", v)
+		default:
+		}
+
+		// The reload of an expired value is sent, as is the load after
+		// the key has been forgotten.
+		time.Sleep(2 * time.Minute)
+		m.Get(context.Background(), "a")
+		if v := <-c; v != 3 {
+			t.Fatalf("got %d after expiry, want 3", v)
+		}
+		m.Forget("a")
+		m.Get(context.Background(), "a")
+		if v := <-c; v != 4 {
+			t.Fatalf("got %d after Forget, want 4", v)
+		}
+
+		cancel()
+		synctest.Wait()
+		if _, ok := <-c; ok {
+			t.Fatal("channel not closed after the context is done")
+		}
+	})
+}
+
+func TestMap_WatchClose(t *testing.T) {
+	m := NewMap(func(ctx context.Context, key string) (int, error) { return 1, nil })
+	c := m.Watch(context.Background(), "a")
+	m.Close()
+	if _, ok := <-c; ok {
+		t.Fatal("channel not closed by Close")
+	}
+	if _, ok := <-m.Watch(context.Background(), "a"); ok {
+		t.Fatal("channel of a closed Map not closed")
+	}
+}
@@ -0,0 +1,68 @@
+package lazy
+
+import (
+	"context"
+	"weak"
+)
+
+// A WeakMap is like a Map whose values are pointers, but holds its values
+// through weak pointers, so that the garbage collector may reclaim a value
+// that is no longer referenced outside the WeakMap. The next Get of its key
+// then loads it anew. It suits large values that are cheap enough to rebuild
+// and should not be kept alive by the cache alone. A WeakMap must be created
+// with NewWeakMap and is safe for concurrent use by multiple goroutines.
+type WeakMap[K, T any] struct {
+	m *Map[K, weakValue[T]]
+}
+
+// A weakValue is a value of a WeakMap.
+type weakValue[T any] struct {
+	p     weak.Pointer[T]
+	isNil bool // whether the loaded pointer was nil
+}
+
+// NewWeakMap returns a WeakMap whose values are loaded by calling load with
+// their key. The options are those of a Map.
+func NewWeakMap[K comparable, T any](load func(context.Context, K) (*T, error), opts ...Option) *WeakMap[K, T] {
+	return &WeakMap[K, T]{
+		m: NewMap(func(ctx context.Context, key K) (weakValue[T], error) {
+			p, err := load(ctx, key)
+			if err != nil {
+				return weakValue[T]{}, err
+			}
+			return weakValue[T]{p: weak.Make(p), isNil: p == nil}, nil
+		}, opts...),
+	}
+}
+
+// Get returns the value for key, loading it if no value has been cached yet,
+// the cached value has expired, or it has been reclaimed by the garbage
+// collector.
+func (w *WeakMap[K, T]) Get(ctx context.Context, key K) (*T, error) {
+	for {
+		v, err := w.m.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if p := v.p.Value(); p != nil || v.isNil {
+			return p, nil
+		}
+		w.m.forgetValue(key, v)
+	}
+}
+
+// Forget removes the cached value for key, if any, as Map.Forget does.
+func (w *WeakMap[K, T]) Forget(key K) {
+	w.m.Forget(key)
+}
+
+// ForgetAll removes all cached values from w.
+func (w *WeakMap[K, T]) ForgetAll() {
+	w.m.ForgetAll()
+}
+
+// Close closes w for good, as Map.Close does: later calls of Get return
+// ErrClosed.
+func (w *WeakMap[K, T]) Close() {
+	w.m.Close()
+}
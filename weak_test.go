@@ -0,0 +1,52 @@
+package lazy
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+type weakTestValue struct {
+	data [1 << 10]byte
+	n    int32
+}
+
+func TestWeakMap(t *testing.T) {
+	var calls atomic.Int32
+	w := NewWeakMap(func(ctx context.Context, key string) (*weakTestValue, error) {
+		return &weakTestValue{n: calls.Add(1)}, nil
+	})
+
+	ctx := context.Background()
+	p, _ := w.Get(ctx, "a")
+	runtime.GC()
+	if q, _ := w.Get(ctx, "a"); q != p {
+		t.Fatal("got a new value while the cached one is referenced")
+	}
+	runtime.KeepAlive(p)
+
+	p = nil
+	runtime.GC()
+	if q, _ := w.Get(ctx, "a"); q.n != 2 {
+		t.Fatalf("got value %d after it was reclaimed, want 2", q.n)
+	}
+}
+
+func TestWeakMap_Nil(t *testing.T) {
+	var calls atomic.Int32
+	w := NewWeakMap(func(ctx context.Context, key string) (*weakTestValue, error) {
+		calls.Add(1)
+		return nil, nil
+	})
+
+	ctx := context.Background()
+	for range 2 {
+		if p, err := w.Get(ctx, "a"); p != nil || err != nil {
+			t.Fatalf("got (%v, %v), want (nil, nil)", p, err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("load called %d times, want 1", got)
+	}
+}